@@ -1,11 +1,19 @@
 package api
 
 import (
+	"bytes"
+	"encoding"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Sets the full schema on the given builder with the defined generic type.
@@ -50,6 +58,10 @@ type Builder struct {
 	SliceIsNullable bool
 	// If a map without omitempty can be nullable
 	MapIsNullable bool
+	// If Build() should return an OpenAPI 3.1 document instead of 3.0. Schemas use JSON Schema
+	// 2020-12 keywords like type arrays for nullable values, numeric exclusive bounds, and examples,
+	// and webhooks are included. Schemas are built in 3.0 form and converted when the document is built.
+	OpenAPI31 bool
 	// All collisions that occurred on the last Build().
 	Collisions map[reflect.Type]*Schema
 	// A schema can be defined with starting values, when it's first built it will
@@ -63,6 +75,7 @@ type Builder struct {
 
 	schemas         map[reflect.Type]*Schema
 	paths           map[string]*Path
+	webhooks        map[string]*Path
 	responses       map[string]*Response
 	parameters      map[string]*Parameter
 	examples        map[string]*Example
@@ -82,6 +95,7 @@ func NewBuilder() *Builder {
 
 		schemas:         make(map[reflect.Type]*Schema),
 		paths:           make(map[string]*Path),
+		webhooks:        make(map[string]*Path),
 		responses:       make(map[string]*Response),
 		parameters:      make(map[string]*Parameter),
 		examples:        make(map[string]*Example),
@@ -98,6 +112,63 @@ func (build *Builder) BuildJSON() []byte {
 	return json
 }
 
+// Builds the document and returns it as YAML, with the keys in the same order as the JSON.
+func (build *Builder) BuildYAML() []byte {
+	yaml, _ := encodeYAML(build.Build())
+	return yaml
+}
+
+// Writes the built document to the file at path, as YAML if the path ends in .yaml or .yml
+// and indented JSON otherwise. If the file exists with a different document it's replaced
+// and an error is returned, so a test can keep a snapshot of the document in the repository
+// which fails when the API changes and leaves the new document to be reviewed and committed.
+//
+//	func TestSpec(t *testing.T) {
+//		if err := site.WriteSpec("testdata/openapi.yaml"); err != nil {
+//			t.Error(err)
+//		}
+//	}
+func (build *Builder) WriteSpec(path string) error {
+	var spec []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		spec, err = encodeYAML(build.Build())
+	default:
+		spec, err = json.MarshalIndent(build.Build(), "", "  ")
+		spec = append(spec, '\n')
+	}
+	if err != nil {
+		return err
+	}
+
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if bytes.Equal(existing, spec) {
+		return nil
+	}
+	if err := os.WriteFile(path, spec, 0644); err != nil {
+		return err
+	}
+	if existing == nil {
+		return nil
+	}
+	return fmt.Errorf("the API document at %s changed at line %d, review and commit it", path, firstChangedLine(existing, spec))
+}
+
+// Returns the first line (starting at 1) which is different between a and b.
+func firstChangedLine(a []byte, b []byte) int {
+	line := 1
+	for i := 0; i < len(a) && i < len(b) && a[i] == b[i]; i++ {
+		if a[i] == '\n' {
+			line++
+		}
+	}
+	return line
+}
+
 // Builds the current document.
 func (build *Builder) Build() Document {
 	doc := build.Document
@@ -113,16 +184,8 @@ func (build *Builder) Build() Document {
 		if doc.Components.Schemas == nil {
 			doc.Components.Schemas = make(map[string]Schema)
 		}
-		for typ := range build.Names {
-			schema := build.schemas[typ]
-			if schema != nil {
-				build.setDocumentSchema(&doc, schema, typ)
-			}
-		}
-		for typ, schema := range build.schemas {
-			if _, named := build.Names[typ]; !named {
-				build.setDocumentSchema(&doc, schema, typ)
-			}
+		for _, typ := range build.schemaTypes() {
+			build.setDocumentSchema(&doc, build.schemas[typ], typ)
 		}
 	}
 
@@ -193,9 +256,52 @@ func (build *Builder) Build() Document {
 		doc.Paths[url] = *path
 	}
 
+	if build.OpenAPI31 {
+		if len(build.webhooks) > 0 {
+			webhooks := make(map[string]Path, len(doc.Webhooks)+len(build.webhooks))
+			for name, webhook := range doc.Webhooks {
+				webhooks[name] = webhook
+			}
+			for name, webhook := range build.webhooks {
+				webhooks[name] = *webhook
+			}
+			doc.Webhooks = webhooks
+		}
+		doc = upgradeDocument(doc)
+	}
+
 	return doc
 }
 
+// Returns the types with schemas in the order they're added to the document. Types with
+// a name set on the builder are first, then the rest, each ordered by their qualified
+// name so collisions are resolved the same way on every build.
+func (build *Builder) schemaTypes() []reflect.Type {
+	named := make([]reflect.Type, 0, len(build.Names))
+	unnamed := make([]reflect.Type, 0, len(build.schemas))
+	for typ := range build.schemas {
+		if _, exists := build.Names[typ]; exists {
+			named = append(named, typ)
+		} else {
+			unnamed = append(unnamed, typ)
+		}
+	}
+	sortTypes(named)
+	sortTypes(unnamed)
+	return append(named, unnamed...)
+}
+
+// Sorts types by their qualified name and then their Go name.
+func sortTypes(types []reflect.Type) {
+	sort.Slice(types, func(i, j int) bool {
+		a, b := GetNameQualified(types[i]), GetNameQualified(types[j])
+		if a != b {
+			return a < b
+		}
+		return types[i].String() < types[j].String()
+	})
+}
+
 // Sets the name of the type based on the value.
 func (build *Builder) SetName(typ reflect.Type, name string) {
 	build.Names[typ] = name
@@ -253,6 +359,17 @@ func (build *Builder) RefPath(url string) *Path {
 	return path
 }
 
+// Adds a webhook to the builder, which is a request the API may send that the API consumer
+// can choose to implement. Webhooks are only in OpenAPI 3.1 documents (see Builder.OpenAPI31).
+func (build *Builder) AddWebhook(name string, path *Path) {
+	build.webhooks[name] = path
+}
+
+// Gets the webhook with the given name.
+func (build *Builder) GetWebhook(name string) *Path {
+	return build.webhooks[name]
+}
+
 // Adds a named response to the builder.
 func (build *Builder) AddResponse(name string, response *Response) {
 	response.named = RefTo(response, name)
@@ -500,6 +617,18 @@ func (build *Builder) BuildSchema(typ reflect.Type, addToDocument bool) *Schema
 	// Use an alternative type for ths schema (possibly).
 	schemaType := GetSchemaType(typ)
 
+	// Types which parse themselves from text (like time.Time or a UUID) are strings,
+	// unless they have their own JSON (like big.Int which is a number) which can't be known.
+	if isTextUnmarshaler(schemaType) {
+		if schemaType == timeType {
+			s.Type = MergeValue(s.Type, DataTypeString)
+			s.Format = MergeValue(s.Format, "date-time")
+		} else if !isJSONMarshaler(schemaType) {
+			s.Type = MergeValue(s.Type, DataTypeString)
+		}
+		return s
+	}
+
 	// Coalesce ensures we don't override non-zero values returned by APISchema
 	switch schemaType.Kind() {
 	// Unsupported types
@@ -557,6 +686,27 @@ func (build *Builder) BuildSchema(typ reflect.Type, addToDocument bool) *Schema
 	return s
 }
 
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+var timeType = reflect.TypeOf(time.Time{})
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// Returns whether values of the type are parsed from text with encoding.TextUnmarshaler.
+// Strings and interfaces are not text types.
+func isTextUnmarshaler(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.String, reflect.Interface, reflect.Pointer:
+		return false
+	}
+	return reflect.PointerTo(typ).Implements(textUnmarshalerType)
+}
+
+// Returns whether values of the type are marshaled or unmarshaled with their own JSON.
+func isJSONMarshaler(typ reflect.Type) bool {
+	pointer := reflect.PointerTo(typ)
+	return pointer.Implements(jsonMarshalerType) || pointer.Implements(jsonUnmarshalerType)
+}
+
 // A map from GO kind to format.
 var KindToFormat = map[reflect.Kind]string{
 	reflect.Float32: "float",
@@ -785,6 +935,9 @@ func ApplyOptions(s *Schema, tag string) {
 			s.ReadOnly = true
 		case "writeonly":
 			s.WriteOnly = true
+		case "default":
+			defaultValue := parseDefault(s, value)
+			s.Default = &defaultValue
 		case "enum":
 			s.Enum = make([]any, 0)
 			enumConstants := splitWithEscape(value, "|", "\\")
@@ -857,3 +1010,55 @@ func ApplyOptions(s *Schema, tag string) {
 		}
 	}
 }
+
+// Parses the default value given in a tag for the schema. Strings are used as is,
+// other types are parsed as JSON.
+func parseDefault(s *Schema, value string) any {
+	switch getDataType(s) {
+	case DataTypeString:
+		return value
+	case DataTypeInteger:
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			panic(fmt.Sprintf("Error parsing default from tag: %s", value))
+		}
+		return parsed
+	case DataTypeNumber:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			panic(fmt.Sprintf("Error parsing default from tag: %s", value))
+		}
+		return parsed
+	case DataTypeBoolean:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			panic(fmt.Sprintf("Error parsing default from tag: %s", value))
+		}
+		return parsed
+	}
+
+	var parsed any
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		return value
+	}
+	return parsed
+}
+
+// Returns the type of the schema, looking through references and nullable wrappers.
+func getDataType(s *Schema) DataType {
+	for s != nil {
+		s = s.ResolveReference()
+		if s.Type != "" && s.Type != DataTypeNull {
+			return s.Type
+		}
+		switch {
+		case len(s.AllOf) == 1:
+			s = &s.AllOf[0]
+		case len(s.OneOf) == 2 && s.OneOf[1].Type == DataTypeNull:
+			s = &s.OneOf[0]
+		default:
+			return ""
+		}
+	}
+	return ""
+}
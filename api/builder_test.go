@@ -3,6 +3,9 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -92,6 +95,234 @@ func TestBuild(t *testing.T) {
 	assert.Equal(string(doc), `{"openapi":"3.0.0","info":{"title":"Test","version":""},"paths":{"/tasks/{id}":{"description":"Actions for tasks","get":{"summary":"Get task","parameters":[{"name":"id","in":"path","required":true,"schema":{"$ref":"#/components/schemas/UUID"}}],"responses":{"200":{"description":"","content":{"application/json":{"schema":{"type":"object","required":["id","name","done"],"properties":{"done":{"type":"boolean"},"doneAt":{"oneOf":[{"$ref":"#/components/schemas/Time"},{"type":"null"}]},"id":{"$ref":"#/components/schemas/UUID"},"name":{"type":"string"}},"additionalProperties":false}}}}}}}},"components":{"schemas":{"TaskResult":{"type":"object","required":["id","name","done"],"properties":{"done":{"type":"boolean"},"doneAt":{"oneOf":[{"$ref":"#/components/schemas/Time"},{"type":"null"}]},"id":{"$ref":"#/components/schemas/UUID"},"name":{"type":"string"}},"additionalProperties":false},"TestA":{"type":"object","required":["Name","FavoriteNumbers"],"properties":{"Age":{"type":"integer","nullable":true},"FavoriteNumbers":{"type":"array","items":{"type":"number"}},"Name":{"type":"string"}},"additionalProperties":false},"TestB":{"type":"object","required":["id","IntMap","AMap"],"properties":{"AMap":{"type":"object","additionalProperties":{"oneOf":[{"$ref":"#/components/schemas/TestA"},{"type":"null"}]}},"IntMap":{"type":"object","additionalProperties":{"type":"integer"}},"bool":{"type":"boolean"},"id":{"type":"integer"}},"additionalProperties":false},"Time":{"type":"string","format":"date"},"UUID":{"type":"string","description":"A universally unique identifier","format":"uuid"}}}}`)
 }
 
+type TestColor string
+
+func (c TestColor) APIExample() *any {
+	var example any = "red"
+	return &example
+}
+
+func TestBuildOpenAPI31(t *testing.T) {
+	assert := assert.New(t)
+
+	type Paint struct {
+		Color  *TestColor `json:"color"`
+		Amount *int       `json:"amount" api:"minimum=0,exclusivemin"`
+		Owner  *UUID      `json:"owner,omitempty"`
+		Extra  *any       `json:"extra,omitempty"`
+	}
+
+	b := NewBuilder()
+	b.OpenAPI31 = true
+	b.Document = Document{
+		OpenAPI: "3.0.0",
+		Info:    Info{Title: "Test"},
+	}
+	b.AddSchema(reflect.TypeOf(Paint{}))
+	b.AddWebhook("newPaint", &Path{
+		Post: &Operation{
+			RequestBody: &RequestBody{
+				Content: Contents{
+					ContentTypeJSON: &MediaType{Schema: b.GetSchema(reflect.TypeOf(Paint{}))},
+				},
+			},
+			Responses: Responses{"200": &Response{Description: "Received"}},
+		},
+	})
+
+	doc := b.Build()
+	data, _ := json.Marshal(doc)
+
+	assert.Equal(`{"openapi":"3.1.0","info":{"title":"Test","version":""},"paths":{},"webhooks":{"newPaint":{"post":{"requestBody":{"content":{"application/json":{"schema":{"type":"object","required":["color","amount"],"properties":{"amount":{"type":["integer","null"],"exclusiveMinimum":0},"color":{"type":["string","null"],"examples":["red"]},"extra":{},"owner":{"anyOf":[{"$ref":"#/components/schemas/UUID"},{"type":"null"}]}},"additionalProperties":false}}}},"responses":{"200":{"description":"Received"}}}}},"components":{"schemas":{"Paint":{"type":"object","required":["color","amount"],"properties":{"amount":{"type":["integer","null"],"exclusiveMinimum":0},"color":{"type":["string","null"],"examples":["red"]},"extra":{},"owner":{"anyOf":[{"$ref":"#/components/schemas/UUID"},{"type":"null"}]}},"additionalProperties":false},"UUID":{"type":"string","description":"A universally unique identifier","format":"uuid"}}}}`, string(data))
+
+	paint := doc.Components.Schemas["Paint"]
+	assert.Equal("Paint", *paint.GetName())
+	assert.Equal("UUID", *paint.Properties["owner"].AnyOf[0].ResolveReference().GetName())
+
+	// The builder's schemas are not converted
+	schema := b.GetSchema(reflect.TypeOf(Paint{}))
+	assert.True(schema.Properties["amount"].Nullable)
+	assert.True(schema.Properties["amount"].ExclusiveMinimum)
+
+	parsed := Document{}
+	if assert.NoError(json.Unmarshal(data, &parsed)) {
+		amount := parsed.Components.Schemas["Paint"].Properties["amount"]
+		assert.Equal([]DataType{DataTypeInteger, DataTypeNull}, amount.Types)
+		assert.Equal(0.0, *amount.ExclusiveMinimumValue)
+
+		reencoded, _ := json.Marshal(parsed)
+		assert.Equal(string(data), string(reencoded))
+	}
+
+	ratio := `{"type":"number","exclusiveMaximum":1.5,"exclusiveMinimum":0.25}`
+	parsedRatio := Schema{}
+	if assert.NoError(json.Unmarshal([]byte(ratio), &parsedRatio)) {
+		assert.Equal(0.25, *parsedRatio.ExclusiveMinimumValue)
+		assert.Equal(1.5, *parsedRatio.ExclusiveMaximumValue)
+
+		reencoded, _ := json.Marshal(parsedRatio)
+		assert.Equal(ratio, string(reencoded))
+	}
+}
+
+func TestBuildJSONSchema(t *testing.T) {
+	assert := assert.New(t)
+
+	type Folder struct {
+		Name   string  `json:"name"`
+		Parent *Folder `json:"parent,omitempty"`
+		Owner  *UUID   `json:"owner,omitempty"`
+	}
+
+	b := NewBuilder()
+	b.Document = Document{
+		OpenAPI: "3.0.0",
+		Info:    Info{Title: "Test"},
+	}
+
+	schema := b.BuildJSONSchema(reflect.TypeOf([]Folder{}))
+	data, _ := json.Marshal(schema)
+	assert.Equal(`{"type":"array","items":{"$ref":"#/$defs/Folder"},"$defs":{"Folder":{"type":"object","required":["name"],"properties":{"name":{"type":"string"},"owner":{"anyOf":[{"$ref":"#/$defs/UUID"},{"type":"null"}]},"parent":{"anyOf":[{"$ref":"#/$defs/Folder"},{"type":"null"}]}},"additionalProperties":false},"UUID":{"type":"string","description":"A universally unique identifier","format":"uuid"}}}`, string(data))
+
+	// The document stays OpenAPI 3.0
+	assert.Contains(string(b.BuildJSON()), `"owner":{"oneOf":[{"$ref":"#/components/schemas/UUID"},{"type":"null"}]}`)
+
+	parsed := Schema{}
+	if assert.NoError(json.Unmarshal(data, &parsed)) {
+		assert.Equal("#/$defs/UUID", parsed.Defs["Folder"].Properties["owner"].AnyOf[0].Ref)
+	}
+}
+
+type TestCode struct {
+	value string
+}
+
+func (c *TestCode) UnmarshalText(text []byte) error {
+	c.value = string(text)
+	return nil
+}
+
+func TestBuildTextTypes(t *testing.T) {
+	assert := assert.New(t)
+
+	type Payment struct {
+		Code   TestCode  `json:"code"`
+		At     time.Time `json:"at"`
+		Amount big.Int   `json:"amount"`
+	}
+
+	b := NewBuilder()
+	b.Document = Document{OpenAPI: "3.0.0", Info: Info{Title: "Payments"}}
+	b.AddSchema(reflect.TypeOf(Payment{}))
+
+	doc := b.Build()
+	data, _ := json.Marshal(doc.Components.Schemas)
+	assert.Equal(`{"Int":{},"Payment":{"type":"object","required":["code","at","amount"],"properties":{"amount":{"$ref":"#/components/schemas/Int"},"at":{"$ref":"#/components/schemas/Time"},"code":{"$ref":"#/components/schemas/TestCode"}},"additionalProperties":false},"TestCode":{"type":"string"},"Time":{"type":"string","format":"date-time"}}`, string(data))
+}
+
+func TestBuildYAML(t *testing.T) {
+	assert := assert.New(t)
+
+	type Flag struct {
+		Name  string          `json:"name"`
+		Value TestColor       `json:"value"`
+		Extra map[string]bool `json:"extra,omitempty"`
+	}
+
+	b := NewBuilder()
+	b.Document = Document{
+		OpenAPI: "3.0.0",
+		Info:    Info{Title: "true", Version: "1.0"},
+	}
+	b.AddSchema(reflect.TypeOf(Flag{}))
+
+	assert.Equal(`openapi: 3.0.0
+info:
+  title: "true"
+  version: "1.0"
+paths: {}
+components:
+  schemas:
+    Flag:
+      type: object
+      required:
+        - name
+        - value
+      properties:
+        extra:
+          type: object
+          additionalProperties:
+            type: boolean
+        name:
+          type: string
+        value:
+          type: string
+          example: red
+      additionalProperties: false
+`, string(b.BuildYAML()))
+
+	fromYAML, err := ParseDocument(b.BuildYAML())
+	if assert.NoError(err) {
+		data, _ := json.Marshal(fromYAML)
+		assert.Equal(string(b.BuildJSON()), string(data))
+	}
+}
+
+type TestNameA struct{}
+
+func (t TestNameA) APIName() string { return "Shared" }
+
+type TestNameB struct{}
+
+func (t TestNameB) APIName() string { return "Shared" }
+
+func TestBuildCollisions(t *testing.T) {
+	assert := assert.New(t)
+
+	for i := 0; i < 10; i++ {
+		b := NewBuilder()
+		b.AddSchema(reflect.TypeOf(TestNameB{}))
+		b.AddSchema(reflect.TypeOf(TestNameA{}))
+		b.AddSchema(reflect.TypeOf(TestHasName{}))
+
+		doc := b.Build()
+		assert.Len(doc.Components.Schemas, 3)
+		assert.Equal(reflect.TypeOf(TestNameA{}), doc.Components.Schemas["Shared"].typ)
+		assert.Equal(reflect.TypeOf(TestNameB{}), doc.Components.Schemas["GithubComClickerMonkeyRezApiShared"].typ)
+	}
+}
+
+func TestWriteSpec(t *testing.T) {
+	assert := assert.New(t)
+
+	type Item struct {
+		Name string `json:"name"`
+	}
+
+	b := NewBuilder()
+	b.Document = Document{OpenAPI: "3.0.0", Info: Info{Title: "Items"}}
+	b.AddSchema(reflect.TypeOf(Item{}))
+
+	path := filepath.Join(t.TempDir(), "openapi.yaml")
+	assert.NoError(b.WriteSpec(path))
+	assert.NoError(b.WriteSpec(path))
+
+	written, _ := os.ReadFile(path)
+	assert.Equal(string(b.BuildYAML()), string(written))
+
+	type Other struct {
+		ID int `json:"id"`
+	}
+	b.AddSchema(reflect.TypeOf(Other{}))
+	assert.EqualError(b.WriteSpec(path), "the API document at "+path+" changed at line 16, review and commit it")
+	assert.NoError(b.WriteSpec(path))
+
+	jsonPath := filepath.Join(t.TempDir(), "openapi.json")
+	assert.NoError(b.WriteSpec(jsonPath))
+	written, _ = os.ReadFile(jsonPath)
+	assert.Contains(string(written), "\n  \"openapi\": \"3.0.0\",\n")
+}
+
 type TestHasName struct{}
 
 func (t TestHasName) APIName() string { return "TestHasNameAlias" }
@@ -119,6 +350,25 @@ func TestGetEnum(t *testing.T) {
 	assert.Equal([]any{"A", "B"}, GetEnums(typeOf[TestHasEnum]()))
 }
 
+func TestApplyOptionsDefault(t *testing.T) {
+	assert := assert.New(t)
+
+	getDefault := func(s Schema, tag string) any {
+		ApplyOptions(&s, tag)
+		return *s.Default
+	}
+
+	assert.Equal("a,b", getDefault(Schema{Type: DataTypeString}, `default=a\,b`))
+	assert.Equal(int64(20), getDefault(Schema{Type: DataTypeInteger}, "min=1,default=20"))
+	assert.Equal(0.5, getDefault(Schema{Type: DataTypeNumber}, "default=0.5"))
+	assert.Equal(true, getDefault(Schema{OneOf: []Schema{{Type: DataTypeBoolean}, {Type: DataTypeNull}}}, "default=true"))
+	assert.Equal([]any{"x"}, getDefault(Schema{Type: DataTypeArray}, `default=["x"]`))
+	assert.Equal("today", getDefault(Schema{}, "default=today"))
+	assert.Panics(func() {
+		getDefault(Schema{Type: DataTypeInteger}, "default=many")
+	})
+}
+
 func TestGetNameQualified(t *testing.T) {
 	assert := assert.New(t)
 
@@ -0,0 +1,637 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A difference between two versions of a document.
+type Change struct {
+	// Where the change is, like "GET /tasks/{id} response 200 application/json .name".
+	Location string
+	// What changed, like "property removed".
+	Message string
+	// If clients of the old document may not work with the new document.
+	Breaking bool
+}
+
+func (c Change) String() string {
+	kind := "non-breaking"
+	if c.Breaking {
+		kind = "breaking"
+	}
+	return kind + ": " + c.Location + ": " + c.Message
+}
+
+// The changes between two versions of a document.
+type Changes []Change
+
+// Returns only the breaking changes.
+func (changes Changes) Breaking() Changes {
+	breaking := make(Changes, 0, len(changes))
+	for _, change := range changes {
+		if change.Breaking {
+			breaking = append(breaking, change)
+		}
+	}
+	return breaking
+}
+
+// Returns whether any of the changes are breaking.
+func (changes Changes) HasBreaking() bool {
+	for _, change := range changes {
+		if change.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the changes with one per line.
+func (changes Changes) String() string {
+	lines := make([]string, len(changes))
+	for i, change := range changes {
+		lines[i] = change.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Compares two versions of a document and returns what changed between them, ordered by
+// location. A change is breaking if a client of the before document may not work with the
+// after document: an operation, parameter, response, or content type is removed, a parameter
+// or request body becomes required, or a schema changes type. Schemas in requests break
+// clients when what they accept is narrowed (a new required property, a removed enum value,
+// a tighter minimum or maxLength, etc) and schemas in responses break clients when what
+// they can contain is widened (a removed or now optional property, a new enum value, a
+// looser maximum, a value becoming nullable, etc).
+//
+//	changes := api.Diff(released, site.Open.Build())
+//	if changes.HasBreaking() {
+//		t.Error(changes.Breaking())
+//	}
+func Diff(before Document, after Document) Changes {
+	d := &differ{
+		before:    &before,
+		after:     &after,
+		comparing: make(map[string]bool),
+	}
+	d.paths()
+
+	sort.SliceStable(d.changes, func(i, j int) bool {
+		return d.changes[i].Location < d.changes[j].Location
+	})
+
+	return d.changes
+}
+
+type differ struct {
+	before  *Document
+	after   *Document
+	changes Changes
+	// named schemas being compared, to stop at recursive schemas
+	comparing map[string]bool
+}
+
+func (d *differ) add(location string, breaking bool, format string, args ...any) {
+	d.changes = append(d.changes, Change{
+		Location: location,
+		Message:  fmt.Sprintf(format, args...),
+		Breaking: breaking,
+	})
+}
+
+// Adds a change which narrows (or widens) the values a schema allows. Narrowing breaks the
+// clients sending requests and widening breaks the clients reading responses.
+func (d *differ) narrowed(location string, request bool, narrowed bool, format string, args ...any) {
+	d.add(location, request == narrowed, format, args...)
+}
+
+var diffMethods = []struct {
+	name string
+	of   func(path Path) *Operation
+}{
+	{"GET", func(path Path) *Operation { return path.Get }},
+	{"PUT", func(path Path) *Operation { return path.Put }},
+	{"POST", func(path Path) *Operation { return path.Post }},
+	{"DELETE", func(path Path) *Operation { return path.Delete }},
+	{"OPTIONS", func(path Path) *Operation { return path.Options }},
+	{"HEAD", func(path Path) *Operation { return path.Head }},
+	{"PATCH", func(path Path) *Operation { return path.Patch }},
+	{"TRACE", func(path Path) *Operation { return path.Trace }},
+}
+
+func (d *differ) paths() {
+	for _, url := range unionKeys(d.before.Paths, d.after.Paths) {
+		beforePath := d.before.Paths[url]
+		afterPath := d.after.Paths[url]
+
+		for _, method := range diffMethods {
+			before := method.of(beforePath)
+			after := method.of(afterPath)
+			location := method.name + " " + url
+
+			switch {
+			case before == nil && after == nil:
+			case after == nil:
+				d.add(location, true, "operation removed")
+			case before == nil:
+				d.add(location, false, "operation added")
+			default:
+				d.operation(location, beforePath, before, afterPath, after)
+			}
+		}
+	}
+}
+
+func (d *differ) operation(location string, beforePath Path, before *Operation, afterPath Path, after *Operation) {
+	if !before.Deprecated && after.Deprecated {
+		d.add(location, false, "operation deprecated")
+	}
+	d.parameters(location, parametersOf(d.before, beforePath, before), parametersOf(d.after, afterPath, after))
+	d.requestBody(location+" request body", requestBodyOf(d.before, before.RequestBody), requestBodyOf(d.after, after.RequestBody))
+	d.responses(location, before.Responses, after.Responses)
+}
+
+func (d *differ) parameters(location string, before map[string]Parameter, after map[string]Parameter) {
+	for _, key := range unionKeys(before, after) {
+		beforeParam, inBefore := before[key]
+		afterParam, inAfter := after[key]
+		paramLocation := location + " parameter " + key
+
+		switch {
+		case !inAfter:
+			d.add(paramLocation, true, "parameter removed")
+		case !inBefore && afterParam.Required:
+			d.add(paramLocation, true, "required parameter added")
+		case !inBefore:
+			d.add(paramLocation, false, "optional parameter added")
+		default:
+			if !beforeParam.Required && afterParam.Required {
+				d.add(paramLocation, true, "parameter is now required")
+			} else if beforeParam.Required && !afterParam.Required {
+				d.add(paramLocation, false, "parameter is now optional")
+			}
+			d.schema(paramLocation, "", beforeParam.Schema, afterParam.Schema, true)
+		}
+	}
+}
+
+func (d *differ) requestBody(location string, before *RequestBody, after *RequestBody) {
+	switch {
+	case before == nil && after == nil:
+	case after == nil:
+		d.add(location, true, "request body removed")
+	case before == nil:
+		d.add(location, after.Required, "request body added")
+	default:
+		if !before.Required && after.Required {
+			d.add(location, true, "request body is now required")
+		}
+		d.content(location, before.Content, after.Content, true)
+	}
+}
+
+func (d *differ) responses(location string, before Responses, after Responses) {
+	for _, status := range unionKeys(before, after) {
+		beforeResponse := responseOf(d.before, before[status])
+		afterResponse := responseOf(d.after, after[status])
+		responseLocation := location + " response " + status
+
+		switch {
+		case beforeResponse == nil && afterResponse == nil:
+		case afterResponse == nil:
+			d.add(responseLocation, strings.HasPrefix(status, "2"), "response removed")
+		case beforeResponse == nil:
+			d.add(responseLocation, false, "response added")
+		default:
+			d.content(responseLocation, beforeResponse.Content, afterResponse.Content, false)
+		}
+	}
+}
+
+func (d *differ) content(location string, before Contents, after Contents, request bool) {
+	for _, contentType := range unionKeys(before, after) {
+		beforeMedia := before[contentType]
+		afterMedia := after[contentType]
+		contentLocation := location + " " + string(contentType)
+
+		switch {
+		case beforeMedia == nil && afterMedia == nil:
+		case afterMedia == nil:
+			d.add(contentLocation, true, "content type removed")
+		case beforeMedia == nil:
+			d.add(contentLocation, false, "content type added")
+		default:
+			d.schema(contentLocation, "", beforeMedia.Schema, afterMedia.Schema, request)
+		}
+	}
+}
+
+// Compares the schemas in a request or response at the given location. The path is where
+// the schemas are within the request or response schema, like ".tags[]".
+func (d *differ) schema(location string, path string, before *Schema, after *Schema, request bool) {
+	if before == nil || after == nil {
+		return
+	}
+	here := location
+	if path != "" {
+		here += " " + path
+	}
+
+	b := flattenSchema(d.before, before)
+	a := flattenSchema(d.after, after)
+
+	if b.name != "" && a.name != "" {
+		key := fmt.Sprintf("%s>%s>%t", b.name, a.name, request)
+		if d.comparing[key] {
+			return
+		}
+		d.comparing[key] = true
+		defer delete(d.comparing, key)
+	}
+
+	beforeTypes, afterTypes := b.types(), a.types()
+	switch {
+	case beforeTypes != "" && afterTypes != "" && beforeTypes != afterTypes:
+		d.add(here, true, "type changed from %s to %s", beforeTypes, afterTypes)
+		return
+	case beforeTypes == "" && afterTypes != "":
+		d.narrowed(here, request, true, "type is now %s", afterTypes)
+	case beforeTypes != "" && afterTypes == "":
+		d.narrowed(here, request, false, "type is no longer %s", beforeTypes)
+	}
+
+	if !b.nullable && a.nullable {
+		d.narrowed(here, request, false, "now nullable")
+	} else if b.nullable && !a.nullable {
+		d.narrowed(here, request, true, "no longer nullable")
+	}
+
+	if b.Format != a.Format {
+		switch {
+		case b.Format == "":
+			d.narrowed(here, request, true, "format %q added", a.Format)
+		case a.Format == "":
+			d.narrowed(here, request, false, "format %q removed", b.Format)
+		default:
+			d.add(here, true, "format changed from %q to %q", b.Format, a.Format)
+		}
+	}
+	if b.Pattern != a.Pattern {
+		switch {
+		case b.Pattern == "":
+			d.narrowed(here, request, true, "pattern %q added", a.Pattern)
+		case a.Pattern == "":
+			d.narrowed(here, request, false, "pattern %q removed", b.Pattern)
+		default:
+			d.add(here, true, "pattern changed from %q to %q", b.Pattern, a.Pattern)
+		}
+	}
+	if b.MultipleOf != a.MultipleOf {
+		d.add(here, true, "multipleOf changed from %d to %d", b.MultipleOf, a.MultipleOf)
+	}
+
+	d.enum(here, b.Enum, a.Enum, request)
+
+	beforeMin, beforeMinExclusive := b.minimum()
+	afterMin, afterMinExclusive := a.minimum()
+	d.lowerBound(here, "minimum", beforeMin, afterMin, request)
+	if beforeMin != nil && afterMin != nil && *beforeMin == *afterMin && beforeMinExclusive != afterMinExclusive {
+		d.narrowed(here, request, afterMinExclusive, "minimum exclusive changed to %t", afterMinExclusive)
+	}
+	beforeMax, beforeMaxExclusive := b.maximum()
+	afterMax, afterMaxExclusive := a.maximum()
+	d.upperBound(here, "maximum", beforeMax, afterMax, request)
+	if beforeMax != nil && afterMax != nil && *beforeMax == *afterMax && beforeMaxExclusive != afterMaxExclusive {
+		d.narrowed(here, request, afterMaxExclusive, "maximum exclusive changed to %t", afterMaxExclusive)
+	}
+
+	d.lowerBound(here, "minLength", bound(b.MinLength), bound(a.MinLength), request)
+	d.upperBound(here, "maxLength", positive(b.MaxLength), positive(a.MaxLength), request)
+	d.lowerBound(here, "minItems", bound(b.MinItems), bound(a.MinItems), request)
+	d.upperBound(here, "maxItems", positive(b.MaxItems), positive(a.MaxItems), request)
+	d.lowerBound(here, "minProperties", bound(b.MinProperties), bound(a.MinProperties), request)
+	d.upperBound(here, "maxProperties", positive(b.MaxProperties), positive(a.MaxProperties), request)
+
+	d.properties(location, path, b, a, request)
+
+	if b.Items != nil && a.Items != nil {
+		d.schema(location, path+"[]", b.Items, a.Items, request)
+	}
+
+	beforeClosed, afterClosed := b.closed(), a.closed()
+	if !beforeClosed && afterClosed {
+		d.narrowed(here, request, true, "additional properties no longer allowed")
+	} else if beforeClosed && !afterClosed {
+		d.narrowed(here, request, false, "additional properties now allowed")
+	}
+	if b.AdditionalProperties != nil && a.AdditionalProperties != nil {
+		d.schema(location, path+"{}", b.AdditionalProperties.Schema, a.AdditionalProperties.Schema, request)
+	}
+}
+
+func (d *differ) properties(location string, path string, b flatSchema, a flatSchema, request bool) {
+	beforeRequired := requiredSet(b.Required)
+	afterRequired := requiredSet(a.Required)
+
+	for _, name := range unionKeys(b.Properties, a.Properties) {
+		beforeProperty, inBefore := b.Properties[name]
+		afterProperty, inAfter := a.Properties[name]
+		propertyPath := path + "." + name
+		propertyLocation := location + " " + propertyPath
+
+		switch {
+		case !inAfter && request:
+			d.add(propertyLocation, a.closed(), "property removed")
+		case !inAfter:
+			d.add(propertyLocation, true, "property removed")
+		case !inBefore && request && afterRequired[name]:
+			d.add(propertyLocation, true, "required property added")
+		case !inBefore:
+			d.add(propertyLocation, false, "property added")
+		default:
+			if !beforeRequired[name] && afterRequired[name] {
+				d.narrowed(propertyLocation, request, true, "property is now required")
+			} else if beforeRequired[name] && !afterRequired[name] {
+				d.narrowed(propertyLocation, request, false, "property is now optional")
+			}
+			d.schema(location, propertyPath, &beforeProperty, &afterProperty, request)
+		}
+	}
+}
+
+func (d *differ) enum(location string, before []any, after []any, request bool) {
+	switch {
+	case len(before) == 0 && len(after) == 0:
+	case len(before) == 0:
+		d.narrowed(location, request, true, "enum added")
+	case len(after) == 0:
+		d.narrowed(location, request, false, "enum removed")
+	default:
+		beforeValues := enumSet(before)
+		afterValues := enumSet(after)
+		for _, value := range unionKeys(beforeValues, afterValues) {
+			if !afterValues[value] {
+				d.narrowed(location, request, true, "enum value %s removed", value)
+			} else if !beforeValues[value] {
+				d.narrowed(location, request, false, "enum value %s added", value)
+			}
+		}
+	}
+}
+
+// Compares a bound where a higher value allows less, like minimum.
+func (d *differ) lowerBound(location string, name string, before *float64, after *float64, request bool) {
+	switch {
+	case before == nil && after == nil:
+	case before == nil:
+		d.narrowed(location, request, true, "%s %v added", name, *after)
+	case after == nil:
+		d.narrowed(location, request, false, "%s %v removed", name, *before)
+	case *after > *before:
+		d.narrowed(location, request, true, "%s raised from %v to %v", name, *before, *after)
+	case *after < *before:
+		d.narrowed(location, request, false, "%s lowered from %v to %v", name, *before, *after)
+	}
+}
+
+// Compares a bound where a lower value allows less, like maximum.
+func (d *differ) upperBound(location string, name string, before *float64, after *float64, request bool) {
+	switch {
+	case before == nil && after == nil:
+	case before == nil:
+		d.narrowed(location, request, true, "%s %v added", name, *after)
+	case after == nil:
+		d.narrowed(location, request, false, "%s %v removed", name, *before)
+	case *after < *before:
+		d.narrowed(location, request, true, "%s lowered from %v to %v", name, *before, *after)
+	case *after > *before:
+		d.narrowed(location, request, false, "%s raised from %v to %v", name, *before, *after)
+	}
+}
+
+// A schema with references resolved and without the wrappers around named schemas (a
+// nullable oneOf or anyOf, or a single allOf). The constraints on the wrappers are kept.
+type flatSchema struct {
+	Schema
+	// the component name of the schema, if any
+	name     string
+	nullable bool
+}
+
+func flattenSchema(doc *Document, s *Schema) flatSchema {
+	flat := flatSchema{}
+	wrappers := make([]*Schema, 0)
+
+	for depth := 0; depth < 32; depth++ {
+		if s.Nullable || nullWrapped(s) != nil {
+			flat.nullable = true
+		}
+		for _, typ := range s.Types {
+			if typ == DataTypeNull {
+				flat.nullable = true
+			}
+		}
+
+		if inner := nullWrapped(s); inner != nil {
+			wrappers = append(wrappers, s)
+			s = inner
+		} else if len(s.AllOf) == 1 && len(s.Properties) == 0 {
+			wrappers = append(wrappers, s)
+			s = &s.AllOf[0]
+		} else if target := resolveSchema(doc, s); target != s {
+			if flat.name == "" {
+				flat.name = s.Ref
+			}
+			s = target
+		} else {
+			break
+		}
+	}
+
+	flat.Schema = *s
+	for i := len(wrappers) - 1; i >= 0; i-- {
+		flat.overlay(wrappers[i])
+	}
+	return flat
+}
+
+// Returns the schema which is made nullable by a oneOf (OpenAPI 3.0) or anyOf (OpenAPI 3.1)
+// with null, if any.
+func nullWrapped(s *Schema) *Schema {
+	if len(s.OneOf) == 2 && s.OneOf[1].Type == DataTypeNull {
+		return &s.OneOf[0]
+	}
+	if len(s.AnyOf) == 2 && s.AnyOf[1].Type == DataTypeNull {
+		return &s.AnyOf[0]
+	}
+	return nil
+}
+
+// Applies the constraints on a wrapper schema.
+func (flat *flatSchema) overlay(wrapper *Schema) {
+	if len(wrapper.Enum) > 0 {
+		flat.Enum = wrapper.Enum
+	}
+	flat.Format = MergeValue(flat.Format, wrapper.Format)
+	flat.Pattern = MergeValue(flat.Pattern, wrapper.Pattern)
+	flat.MultipleOf = MergeValue(flat.MultipleOf, wrapper.MultipleOf)
+	flat.Minimum = MergeValue(flat.Minimum, wrapper.Minimum)
+	flat.Maximum = MergeValue(flat.Maximum, wrapper.Maximum)
+	flat.ExclusiveMinimum = flat.ExclusiveMinimum || wrapper.ExclusiveMinimum
+	flat.ExclusiveMaximum = flat.ExclusiveMaximum || wrapper.ExclusiveMaximum
+	flat.ExclusiveMinimumValue = MergeValue(flat.ExclusiveMinimumValue, wrapper.ExclusiveMinimumValue)
+	flat.ExclusiveMaximumValue = MergeValue(flat.ExclusiveMaximumValue, wrapper.ExclusiveMaximumValue)
+	flat.MinLength = MergeValue(flat.MinLength, wrapper.MinLength)
+	flat.MaxLength = MergeValue(flat.MaxLength, wrapper.MaxLength)
+	flat.MinItems = MergeValue(flat.MinItems, wrapper.MinItems)
+	flat.MaxItems = MergeValue(flat.MaxItems, wrapper.MaxItems)
+	flat.MinProperties = MergeValue(flat.MinProperties, wrapper.MinProperties)
+	flat.MaxProperties = MergeValue(flat.MaxProperties, wrapper.MaxProperties)
+}
+
+// The types of the schema other than null, sorted and joined with |.
+func (flat flatSchema) types() string {
+	types := make([]string, 0, 2)
+	if flat.Type != "" && flat.Type != DataTypeNull {
+		types = append(types, string(flat.Type))
+	}
+	for _, typ := range flat.Types {
+		if typ != DataTypeNull {
+			types = append(types, string(typ))
+		}
+	}
+	sort.Strings(types)
+	return strings.Join(types, "|")
+}
+
+// The minimum and whether it's exclusive, in OpenAPI 3.0 or 3.1 form.
+func (flat flatSchema) minimum() (*float64, bool) {
+	if flat.ExclusiveMinimumValue != nil {
+		return flat.ExclusiveMinimumValue, true
+	}
+	return bound(flat.Minimum), flat.ExclusiveMinimum
+}
+
+// The maximum and whether it's exclusive, in OpenAPI 3.0 or 3.1 form.
+func (flat flatSchema) maximum() (*float64, bool) {
+	if flat.ExclusiveMaximumValue != nil {
+		return flat.ExclusiveMaximumValue, true
+	}
+	return bound(flat.Maximum), flat.ExclusiveMaximum
+}
+
+// Returns whether the object does not allow properties other than its properties.
+func (flat flatSchema) closed() bool {
+	return flat.AdditionalProperties != nil && flat.AdditionalProperties.Bool != nil && !*flat.AdditionalProperties.Bool
+}
+
+// Returns the schema a reference points to, or the given schema if it's not a reference
+// to a component schema in the document.
+func resolveSchema(doc *Document, s *Schema) *Schema {
+	if s.Reference == nil || s.Ref == "" {
+		return s
+	}
+	if s.referenced != nil {
+		return s.referenced
+	}
+	if doc.Components != nil {
+		if target, exists := doc.Components.Schemas[refName(s.Ref, componentSchemaPrefix)]; exists {
+			return &target
+		}
+	}
+	return s
+}
+
+// Returns the path and operation parameters of the operation by their location and name.
+func parametersOf(doc *Document, path Path, op *Operation) map[string]Parameter {
+	params := make(map[string]Parameter)
+	for _, list := range [][]Parameter{path.Parameters, op.Parameters} {
+		for _, param := range list {
+			if param.Reference != nil && doc.Components != nil {
+				if resolved, exists := doc.Components.Parameters[refName(param.Ref, param.GetReferencePrefix())]; exists {
+					param = resolved
+				}
+			}
+			params[string(param.In)+" "+param.Name] = param
+		}
+	}
+	return params
+}
+
+func requestBodyOf(doc *Document, body *RequestBody) *RequestBody {
+	if body != nil && body.Reference != nil && doc.Components != nil {
+		if resolved, exists := doc.Components.RequestBodies[refName(body.Ref, body.GetReferencePrefix())]; exists {
+			return &resolved
+		}
+	}
+	return body
+}
+
+func responseOf(doc *Document, response *Response) *Response {
+	if response != nil && response.Reference != nil && doc.Components != nil {
+		if resolved := doc.Components.Responses[refName(response.Ref, response.GetReferencePrefix())]; resolved != nil {
+			return resolved
+		}
+	}
+	return response
+}
+
+// Returns the name of the component a reference points to.
+func refName(ref string, prefix string) string {
+	name := strings.TrimPrefix(ref, prefix)
+	name = strings.ReplaceAll(name, "~1", "/")
+	name = strings.ReplaceAll(name, "~0", "~")
+	return name
+}
+
+// Returns the sorted keys in either map.
+func unionKeys[K ~string, A any, B any](a map[K]A, b map[K]B) []K {
+	keys := make([]K, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, exists := a[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+func requiredSet(required []string) map[string]bool {
+	set := make(map[string]bool, len(required))
+	for _, name := range required {
+		set[name] = true
+	}
+	return set
+}
+
+func enumSet(values []any) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[fmt.Sprintf("%#v", value)] = true
+	}
+	return set
+}
+
+// Returns a pointer to the value if it's set (greater than zero).
+func positive(value int) *float64 {
+	if value <= 0 {
+		return nil
+	}
+	return bound(&value)
+}
+
+// Returns the integer as a bound to compare, or nil if there's none.
+func bound(value *int) *float64 {
+	if value == nil {
+		return nil
+	}
+	converted := float64(*value)
+	return &converted
+}
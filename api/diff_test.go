@@ -0,0 +1,233 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	before, err := ParseDocument([]byte(`
+openapi: 3.0.3
+info:
+  title: Tasks
+  version: "1"
+paths:
+  /tasks:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name:
+                  type: string
+                  maxLength: 100
+                priority:
+                  type: integer
+                  minimum: 0
+              additionalProperties: false
+      responses:
+        "201":
+          description: Created
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Task'
+  /tasks/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: integer
+    get:
+      parameters:
+        - name: fields
+          in: query
+          schema:
+            type: string
+      responses:
+        "200":
+          description: The task
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Task'
+    delete:
+      responses:
+        "204":
+          description: Deleted
+components:
+  schemas:
+    Task:
+      type: object
+      required: [id, name, status]
+      properties:
+        id:
+          type: integer
+        name:
+          type: string
+        status:
+          type: string
+          enum: [open, done]
+        tags:
+          type: array
+          items:
+            type: string
+        parent:
+          $ref: '#/components/schemas/Task'
+`))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	after, err := ParseDocument([]byte(`
+openapi: 3.0.3
+info:
+  title: Tasks
+  version: "2"
+paths:
+  /tasks:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name:
+                  type: string
+                  maxLength: 50
+                priority:
+                  type: integer
+                due:
+                  type: string
+                  format: date
+              additionalProperties: false
+      responses:
+        "201":
+          description: Created
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Task'
+  /tasks/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: string
+    get:
+      parameters:
+        - name: fields
+          in: query
+          schema:
+            type: string
+        - name: org
+          in: query
+          required: true
+          schema:
+            type: string
+      responses:
+        "200":
+          description: The task
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Task'
+        "404":
+          description: Not found
+components:
+  schemas:
+    Task:
+      type: object
+      required: [id, name]
+      properties:
+        id:
+          type: integer
+        name:
+          type: string
+        status:
+          type: string
+          enum: [open, done, archived]
+        parent:
+          $ref: '#/components/schemas/Task'
+`))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	changes := Diff(*before, *after)
+
+	assert.Equal(t, []string{
+		"breaking: DELETE /tasks/{id}: operation removed",
+		"breaking: GET /tasks/{id} parameter path id: type changed from integer to string",
+		"breaking: GET /tasks/{id} parameter query org: required parameter added",
+		"breaking: GET /tasks/{id} response 200 application/json .status: property is now optional",
+		"breaking: GET /tasks/{id} response 200 application/json .status: enum value \"archived\" added",
+		"breaking: GET /tasks/{id} response 200 application/json .tags: property removed",
+		"non-breaking: GET /tasks/{id} response 404: response added",
+		"non-breaking: POST /tasks request body application/json .due: property added",
+		"breaking: POST /tasks request body application/json .name: maxLength lowered from 100 to 50",
+		"non-breaking: POST /tasks request body application/json .priority: minimum 0 removed",
+		"breaking: POST /tasks response 201 application/json .status: property is now optional",
+		"breaking: POST /tasks response 201 application/json .status: enum value \"archived\" added",
+		"breaking: POST /tasks response 201 application/json .tags: property removed",
+	}, lines(changes))
+
+	assert.True(t, changes.HasBreaking())
+	assert.Len(t, changes.Breaking(), 10)
+	assert.Empty(t, Diff(*before, *before))
+}
+
+func lines(changes Changes) []string {
+	out := make([]string, len(changes))
+	for i, change := range changes {
+		out[i] = change.String()
+	}
+	return out
+}
+
+func TestDiffOpenAPI31(t *testing.T) {
+	parse := func(version string, owner string, amount string) *Document {
+		doc, err := ParseDocument([]byte(`
+openapi: ` + version + `
+info:
+  title: Paints
+  version: "1"
+paths:
+  /paints:
+    get:
+      responses:
+        "200":
+          description: The paint
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  owner: ` + owner + `
+                  amount: ` + amount + `
+components:
+  schemas:
+    Owner:
+      type: string
+`))
+		assert.NoError(t, err)
+		return doc
+	}
+
+	before := parse("3.0.3", `{oneOf: [{$ref: '#/components/schemas/Owner'}, {type: "null"}]}`, `{type: number, minimum: 0, exclusiveMinimum: true}`)
+	after := parse("3.1.0", `{anyOf: [{$ref: '#/components/schemas/Owner'}, {type: "null"}]}`, `{type: number, exclusiveMinimum: 0}`)
+	assert.Empty(t, Diff(*before, *after))
+
+	raised := parse("3.1.0", `{anyOf: [{$ref: '#/components/schemas/Owner'}, {type: "null"}]}`, `{type: number, exclusiveMinimum: 0.5}`)
+	assert.Equal(t, []string{
+		"breaking: GET /paints response 200 application/json .amount: minimum lowered from 0.5 to 0",
+	}, lines(Diff(*raised, *after)))
+}
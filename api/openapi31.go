@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// The version of documents built with Builder.OpenAPI31.
+const OpenAPI31Version = "3.1.0"
+
+// Returns a copy of the OpenAPI 3.0 document converted to OpenAPI 3.1 (JSON Schema 2020-12).
+// The schemas in a built document are shared with the builder, which validates requests
+// with them, so they are copied before they are converted.
+func upgradeDocument(doc Document) Document {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return doc
+	}
+	upgraded := Document{}
+	if err := json.Unmarshal(data, &upgraded); err != nil {
+		return doc
+	}
+
+	if !strings.HasPrefix(upgraded.OpenAPI, "3.1") {
+		upgraded.OpenAPI = OpenAPI31Version
+	}
+	schemaWalker(upgradeSchema).document(&upgraded)
+	// Restores schema names and references lost in the copy.
+	_ = resolveDocument(&upgraded)
+
+	return upgraded
+}
+
+// The prefix of references to the schemas in $defs.
+const defsSchemaPrefix = "#/$defs/"
+
+// Builds a standalone JSON Schema (2020-12) of the type, for tools which validate JSON
+// without reading an OpenAPI document. The named schemas it uses are in $defs and are
+// referenced with #/$defs/{name}. Nil is returned if there is no schema for the type.
+func (build *Builder) BuildJSONSchema(typ reflect.Type) *Schema {
+	schema := build.GetSchema(typ)
+	if schema == nil {
+		return nil
+	}
+	doc := build.Build()
+	if !build.OpenAPI31 {
+		doc = upgradeDocument(doc)
+	}
+
+	root := Schema{}
+	data, err := json.Marshal(schema.AsReference())
+	if err != nil || json.Unmarshal(data, &root) != nil {
+		return nil
+	}
+	schemaWalker(upgradeSchema).schema(&root)
+
+	// Moves the named schemas which are referenced, directly or not, to $defs.
+	defs := make(map[string]Schema)
+	pending := []string{}
+	toDefs := schemaWalker(func(s *Schema) {
+		if s.Reference == nil || !strings.HasPrefix(s.Ref, componentSchemaPrefix) {
+			return
+		}
+		name := s.Ref[len(componentSchemaPrefix):]
+		s.SetReference(defsSchemaPrefix + name)
+		if _, exists := defs[name]; exists {
+			return
+		}
+		if named, exists := doc.Components.Schemas[name]; exists {
+			defs[name] = named
+			pending = append(pending, name)
+		}
+	})
+	toDefs.schema(&root)
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+		named := defs[name]
+		toDefs.schema(&named)
+		defs[name] = named
+	}
+	if len(defs) > 0 {
+		root.Defs = defs
+	}
+
+	return &root
+}
+
+// Converts the OpenAPI 3.0 keywords of the schema to their OpenAPI 3.1 equivalents.
+func upgradeSchema(s *Schema) {
+	if s.Type == DataTypeFile {
+		s.Type = DataTypeString
+		if s.Format == "" {
+			s.Format = "binary"
+		}
+	}
+
+	// A schema without a type or composition allows null already.
+	if s.Nullable {
+		s.Nullable = false
+		if s.Type != "" {
+			s.Types = []DataType{s.Type, DataTypeNull}
+			s.Type = ""
+			if len(s.Enum) > 0 {
+				s.Enum = append(s.Enum, nil)
+			}
+		} else if s.Reference != nil || len(s.AllOf) > 0 || len(s.OneOf) > 0 || len(s.AnyOf) > 0 {
+			inner := *s
+			inner.Nullable = false
+			*s = Schema{
+				AnyOf: []Schema{inner, {Type: DataTypeNull}},
+			}
+		}
+	}
+	// A nullable reference is anyOf so it's still valid when the referenced schema allows null.
+	if len(s.OneOf) == 2 && s.OneOf[0].Reference != nil && s.OneOf[1].Type == DataTypeNull && len(s.AnyOf) == 0 {
+		s.AnyOf = s.OneOf
+		s.OneOf = nil
+	}
+
+	if s.ExclusiveMaximum && s.Maximum != nil {
+		maximum := float64(*s.Maximum)
+		s.ExclusiveMaximumValue = &maximum
+		s.Maximum = nil
+	}
+	s.ExclusiveMaximum = false
+	if s.ExclusiveMinimum && s.Minimum != nil {
+		minimum := float64(*s.Minimum)
+		s.ExclusiveMinimumValue = &minimum
+		s.Minimum = nil
+	}
+	s.ExclusiveMinimum = false
+
+	if s.Example != nil {
+		s.Examples = append([]any{*s.Example}, s.Examples...)
+		s.Example = nil
+	}
+}
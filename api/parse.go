@@ -0,0 +1,85 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Parses an OpenAPI 3.0 or 3.1 document in JSON or YAML. References to component schemas
+// are resolved, so ResolveReference on a schema with a $ref returns the component
+// schema, and each component schema has its component name (GetName). If a schema
+// references a component that does not exist an error is returned.
+func ParseDocument(data []byte) (*Document, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] != '{' {
+		converted, err := yamlToJSON(trimmed)
+		if err != nil {
+			return nil, err
+		}
+		trimmed = converted
+	}
+
+	doc := &Document{}
+	if err := json.Unmarshal(trimmed, doc); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("not an OpenAPI 3 document, openapi is %q", doc.OpenAPI)
+	}
+
+	if err := resolveDocument(doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// Links the schemas with a $ref to the component schema they reference, and names
+// the component schemas.
+func resolveDocument(doc *Document) error {
+	schemas := make(map[string]*Schema)
+	missing := make(map[string]bool)
+
+	resolve := schemaWalker(func(s *Schema) {
+		if s.Reference == nil || !strings.HasPrefix(s.Ref, componentSchemaPrefix) {
+			return
+		}
+		if target, exists := schemas[s.Ref]; exists {
+			s.referenced = target
+		} else if !strings.Contains(s.Ref[len(componentSchemaPrefix):], "/") {
+			missing[s.Ref] = true
+		}
+	})
+
+	if doc.Components != nil {
+		for name, schema := range doc.Components.Schemas {
+			schema := schema
+			schema.named = RefTo(&schema, name)
+			schemas[schema.named.Ref] = &schema
+		}
+		for _, schema := range schemas {
+			resolve.schema(schema)
+		}
+		for name, schema := range doc.Components.Schemas {
+			doc.Components.Schemas[name] = *schemas[RefTo(&schema, name).Ref]
+		}
+	}
+
+	resolve.document(doc)
+
+	if len(missing) > 0 {
+		refs := make([]string, 0, len(missing))
+		for ref := range missing {
+			refs = append(refs, ref)
+		}
+		sort.Strings(refs)
+		return fmt.Errorf("unresolved references: %s", strings.Join(refs, ", "))
+	}
+
+	return nil
+}
+
+const componentSchemaPrefix = "#/components/schemas/"
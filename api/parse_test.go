@@ -0,0 +1,61 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDocument(t *testing.T) {
+	yaml := `
+openapi: 3.0.3
+info:
+  title: Pets
+  version: 1.0.0
+paths:
+  /pets/{id}:
+    get:
+      responses:
+        "200":
+          description: The pet.
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name:
+          type: string
+        owner:
+          $ref: '#/components/schemas/Owner'
+    Owner:
+      type: object
+      properties:
+        name:
+          type: string
+`
+	doc, err := ParseDocument([]byte(yaml))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Pets", doc.Info.Title)
+
+	schema := doc.Paths["/pets/{id}"].Get.Responses["200"].Content[ContentTypeJSON].Schema
+	pet := schema.ResolveReference()
+	if assert.NotSame(t, schema, pet) {
+		assert.Equal(t, "Pet", *pet.GetName())
+		owner := pet.Properties["owner"]
+		assert.Equal(t, "Owner", *owner.ResolveReference().GetName())
+		assert.Equal(t, DataTypeString, owner.ResolveReference().Properties["name"].Type)
+	}
+
+	json := `{"openapi":"3.0.0","info":{"title":"Pets","version":"1"},"paths":{},"components":{"schemas":{"Pet":{"type":"object","properties":{"owner":{"$ref":"#/components/schemas/Owner"}}}}}}`
+	_, err = ParseDocument([]byte(json))
+	assert.EqualError(t, err, "unresolved references: #/components/schemas/Owner")
+
+	_, err = ParseDocument([]byte(`{"swagger":"2.0"}`))
+	assert.Error(t, err)
+}
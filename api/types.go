@@ -2,8 +2,11 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -32,7 +35,6 @@ type CanReplace[T any] interface {
 }
 
 // This is the root document object of the OpenAPI document.
-// TODO Webhooks
 type Document struct {
 	// REQUIRED. This string MUST be the semantic version number of the OpenAPI Specification version that the OpenAPI document uses. The openapi field SHOULD be used by tooling specifications and clients to interpret the OpenAPI document. This is not related to the API info.version string.
 	OpenAPI string `json:"openapi"`
@@ -42,6 +44,8 @@ type Document struct {
 	Servers []Server `json:"servers,omitempty"`
 	// REQUIRED. The available paths and operations for the API.
 	Paths map[string]Path `json:"paths"`
+	// OpenAPI 3.1 only. The incoming webhooks that MAY be received as part of this API and that the API consumer MAY choose to implement. The key name is a unique string to refer to each webhook.
+	Webhooks map[string]Path `json:"webhooks,omitempty"`
 	// An element to hold various schemas for the specification.
 	Components *Component `json:"components,omitempty"`
 	// A declaration of which security mechanisms can be used across the API. The list of values includes alternative security requirement objects that can be used. Only one of the security requirement objects need to be satisfied to authorize a request. Individual operations can override this definition. To make security optional, an empty security requirement ({}) can be included in the array.
@@ -61,6 +65,7 @@ func (base Document) Merge(next Document) Document {
 		Info:         *MergeCanMerge(&base.Info, &next.Info),
 		Servers:      MergeSliceReplace(base.Servers, next.Servers),
 		Paths:        MergeMap(base.Paths, next.Paths),
+		Webhooks:     MergeMap(base.Webhooks, next.Webhooks),
 		Components:   MergeCanMerge(base.Components, next.Components),
 		Security:     MergeSliceReplace(base.Security, next.Security),
 		Tags:         MergeSliceReplace(base.Tags, next.Tags),
@@ -218,30 +223,34 @@ type HasReference interface {
 type ContentType string
 
 const (
-	ContentTypeJSON     ContentType = "application/json"
-	ContentTypeXML      ContentType = "application/xml"
-	ContentTypeStream   ContentType = "application/octet-stream"
-	ContentTypeWord     ContentType = "application/msword"
-	ContentTypeGZIP     ContentType = "application/gzip"
-	ContentTypeZIP      ContentType = "application/zip"
-	ContentTypePower    ContentType = "application/vnd.ms-powerpoint"
-	ContentTypeExcel    ContentType = "application/vnd.ms-excel"
-	ContentTypeForm     ContentType = "application/x-www-form-urlencoded"
-	ContentTypeFormData ContentType = "multipart/form-data"
-	ContentTypeText     ContentType = "text/plain"
-	ContentTypeHTML     ContentType = "text/html"
-	ContentTypeCSV      ContentType = "text/csv"
-	ContentTypePNG      ContentType = "image/png"
-	ContentTypeGIF      ContentType = "image/gif"
-	ContentTypeBMP      ContentType = "image/bmp"
-	ContentTypeJPEG     ContentType = "image/jpeg"
-	ContentTypeSVG      ContentType = "image/svg+xml"
-	ContentTypeMP3      ContentType = "audio/mpeg"
-	ContentTypeWAV      ContentType = "audio/wav"
-	ContentTypeMP4      ContentType = "video/mp4"
-	ContentTypeMPEG     ContentType = "video/mpeg"
-	ContentTypeAny      ContentType = "*/*"
-	ContentTypeNone     ContentType = ""
+	ContentTypeJSON        ContentType = "application/json"
+	ContentTypeXML         ContentType = "application/xml"
+	ContentTypeYAML        ContentType = "application/yaml"
+	ContentTypeNDJSON      ContentType = "application/x-ndjson"
+	ContentTypeProblem     ContentType = "application/problem+json"
+	ContentTypeStream      ContentType = "application/octet-stream"
+	ContentTypeWord        ContentType = "application/msword"
+	ContentTypeGZIP        ContentType = "application/gzip"
+	ContentTypeZIP         ContentType = "application/zip"
+	ContentTypePower       ContentType = "application/vnd.ms-powerpoint"
+	ContentTypeExcel       ContentType = "application/vnd.ms-excel"
+	ContentTypeForm        ContentType = "application/x-www-form-urlencoded"
+	ContentTypeFormData    ContentType = "multipart/form-data"
+	ContentTypeText        ContentType = "text/plain"
+	ContentTypeHTML        ContentType = "text/html"
+	ContentTypeCSV         ContentType = "text/csv"
+	ContentTypeEventStream ContentType = "text/event-stream"
+	ContentTypePNG         ContentType = "image/png"
+	ContentTypeGIF         ContentType = "image/gif"
+	ContentTypeBMP         ContentType = "image/bmp"
+	ContentTypeJPEG        ContentType = "image/jpeg"
+	ContentTypeSVG         ContentType = "image/svg+xml"
+	ContentTypeMP3         ContentType = "audio/mpeg"
+	ContentTypeWAV         ContentType = "audio/wav"
+	ContentTypeMP4         ContentType = "video/mp4"
+	ContentTypeMPEG        ContentType = "video/mpeg"
+	ContentTypeAny         ContentType = "*/*"
+	ContentTypeNone        ContentType = ""
 )
 
 // Schema data types
@@ -273,6 +282,8 @@ type Schema struct {
 
 	// The schema type, if there is only one known
 	Type DataType `json:"type,omitempty"`
+	// OpenAPI 3.1 only. The schema types when there is more than one, like ["string", "null"] for a nullable string. When given these are the type instead of Type.
+	Types []DataType `json:"-"`
 	// The title and description keywords must be strings. A “title” will preferably be short, whereas a “description” will provide a more lengthy explanation about the purpose of the data described by the schema.
 	Title string `json:"title,omitempty"`
 	// The title and description keywords must be strings. A “title” will preferably be short, whereas a “description” will provide a more lengthy explanation about the purpose of the data described by the schema.
@@ -283,10 +294,14 @@ type Schema struct {
 	Maximum *int `json:"maximum,omitempty"`
 	// Ranges of numbers are specified using a combination of the minimum and maximum keywords, (or exclusiveMinimum and exclusiveMaximum for expressing exclusive range).
 	ExclusiveMaximum bool `json:"exclusiveMaximum,omitempty"`
+	// OpenAPI 3.1 only. The exclusive maximum value, which is used instead of Maximum and ExclusiveMaximum.
+	ExclusiveMaximumValue *float64 `json:"-"`
 	// Ranges of numbers are specified using a combination of the minimum and maximum keywords, (or exclusiveMinimum and exclusiveMaximum for expressing exclusive range).
 	Minimum *int `json:"minimum,omitempty"`
 	// Ranges of numbers are specified using a combination of the minimum and maximum keywords, (or exclusiveMinimum and exclusiveMaximum for expressing exclusive range).
 	ExclusiveMinimum bool `json:"exclusiveMinimum,omitempty"`
+	// OpenAPI 3.1 only. The exclusive minimum value, which is used instead of Minimum and ExclusiveMinimum.
+	ExclusiveMinimumValue *float64 `json:"-"`
 	// The length of a string can be constrained using the minLength and maxLength keywords. For both keywords, the value must be a non-negative number.
 	MaxLength int `json:"maxLength,omitempty"`
 	// The length of a string can be constrained using the minLength and maxLength keywords. For both keywords, the value must be a non-negative number.
@@ -366,8 +381,13 @@ type Schema struct {
 	ExternalDocs *ExternalDoc `json:"externalDocs,omitempty"`
 	// A free-form property to include an example of an instance for this schema. To represent examples that cannot be naturally represented in JSON or YAML, a string value can be used to contain the example with escaping where necessary.
 	Example *any `json:"example,omitempty"`
+	// OpenAPI 3.1 only. Examples of instances for this schema, which is used instead of Example.
+	Examples []any `json:"examples,omitempty"`
 	// Specifies that a schema is deprecated and SHOULD be transitioned out of usage. Default value is false.
 	Deprecated bool `json:"deprecated,omitempty"`
+	// OpenAPI 3.1 only. Reusable schemas which can be referenced within this schema, like the
+	// named schemas of a schema built with Builder.BuildJSONSchema.
+	Defs map[string]Schema `json:"$defs,omitempty"`
 
 	// Custom content type, used mostly for custom file formats.
 	FileType ContentType `json:"-"`
@@ -375,6 +395,72 @@ type Schema struct {
 
 var _ HasReference = &Schema{}
 var _ CanMerge[Schema] = &Schema{}
+var _ json.Marshaler = &Schema{}
+var _ json.Unmarshaler = &Schema{}
+
+// The fields of a Schema without its JSON methods.
+type schemaFields Schema
+
+// The keywords of a Schema which are different between OpenAPI 3.0 and 3.1.
+type schemaJSON struct {
+	Type any `json:"type,omitempty"`
+	*schemaFields
+	ExclusiveMaximum any `json:"exclusiveMaximum,omitempty"`
+	ExclusiveMinimum any `json:"exclusiveMinimum,omitempty"`
+}
+
+func (s Schema) MarshalJSON() ([]byte, error) {
+	if len(s.Types) == 0 && s.ExclusiveMaximumValue == nil && s.ExclusiveMinimumValue == nil {
+		return json.Marshal(schemaFields(s))
+	}
+	out := schemaJSON{schemaFields: (*schemaFields)(&s)}
+	if len(s.Types) > 0 {
+		out.Type = s.Types
+	} else if s.Type != "" {
+		out.Type = s.Type
+	}
+	if s.ExclusiveMaximumValue != nil {
+		out.ExclusiveMaximum = *s.ExclusiveMaximumValue
+	} else if s.ExclusiveMaximum {
+		out.ExclusiveMaximum = true
+	}
+	if s.ExclusiveMinimumValue != nil {
+		out.ExclusiveMinimum = *s.ExclusiveMinimumValue
+	} else if s.ExclusiveMinimum {
+		out.ExclusiveMinimum = true
+	}
+	return json.Marshal(out)
+}
+
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	in := schemaJSON{schemaFields: (*schemaFields)(s)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	switch t := in.Type.(type) {
+	case string:
+		s.Type = DataType(t)
+	case []any:
+		for _, item := range t {
+			if itemType, ok := item.(string); ok {
+				s.Types = append(s.Types, DataType(itemType))
+			}
+		}
+	}
+	switch m := in.ExclusiveMaximum.(type) {
+	case bool:
+		s.ExclusiveMaximum = m
+	case float64:
+		s.ExclusiveMaximumValue = &m
+	}
+	switch m := in.ExclusiveMinimum.(type) {
+	case bool:
+		s.ExclusiveMinimum = m
+	case float64:
+		s.ExclusiveMinimumValue = &m
+	}
+	return nil
+}
 
 func (base Schema) Merge(next Schema) Schema {
 	if len(base.OneOf) > 0 {
@@ -789,10 +875,27 @@ func (op *Operation) AddParameters(build *Builder, in ParameterIn, typ reflect.T
 	if len(schema.Properties) == 0 {
 		return
 	}
-	for paramName, prop := range schema.Properties {
+	names := make([]string, 0, len(schema.Properties))
+	for paramName := range schema.Properties {
+		names = append(names, paramName)
+	}
+	sort.Strings(names)
+	fields := getPropertyFields(getConcrete(typ))
+	for _, paramName := range names {
+		prop := schema.Properties[paramName]
 		param := Parameter{}
 		param.Name = paramName
 		param.In = in
+		if field, ok := fields[paramName]; ok {
+			param.Style, param.Explode = GetParameterStyle(field.Tag.Get("api"))
+			if param.Style != "" && !param.Style.AllowedIn(in) {
+				panic(fmt.Sprintf("%s parameter %s can't have the %s style", in, paramName, param.Style))
+			}
+			if param.Style == ParameterStyleDeepObject && param.Explode == nil {
+				explode := true
+				param.Explode = &explode
+			}
+		}
 		param.Deprecated = prop.Deprecated
 		param.Example = GetExample(prop)
 
@@ -825,6 +928,30 @@ func (op *Operation) AddParameters(build *Builder, in ParameterIn, typ reflect.T
 		op.Parameters = append(op.Parameters, param)
 	}
 }
+
+// Returns the struct fields of the properties of the type by property name.
+func getPropertyFields(typ reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	if typ.Kind() != reflect.Struct {
+		return fields
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		property, _, skip := GetJSONOptions(field)
+		if skip {
+			continue
+		}
+		if field.Anonymous {
+			for name, embedded := range getPropertyFields(getConcrete(field.Type)) {
+				fields[name] = embedded
+			}
+		} else {
+			fields[property] = field
+		}
+	}
+	return fields
+}
+
 func (op *Operation) GetParameters(in ParameterIn) []Parameter {
 	params := make([]Parameter, 0, len(op.Parameters))
 	if len(op.Parameters) > 0 {
@@ -915,6 +1042,72 @@ const (
 	ParameterStyleDeepObject Style = "deepObject"
 )
 
+// The locations each style can be used in.
+var styleLocations = map[Style][]ParameterIn{
+	ParameterStyleMatrix:         {ParameterInPath},
+	ParameterStyleLabel:          {ParameterInPath},
+	ParameterStyleForm:           {ParameterInQuery, ParameterInCookie},
+	ParameterStyleSimple:         {ParameterInPath, ParameterInHeader},
+	ParameterStyleSpaceDelimited: {ParameterInQuery},
+	ParameterStylePipeDelimited:  {ParameterInQuery},
+	ParameterStyleDeepObject:     {ParameterInQuery},
+}
+
+// Returns whether the style can be used for parameters in the location.
+func (style Style) AllowedIn(in ParameterIn) bool {
+	for _, allowed := range styleLocations[style] {
+		if allowed == in {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the style and explode of a parameter in the location with the defaults applied
+// when they are not given: form for query and cookie parameters and simple for path and
+// header parameters, and only the form and deepObject styles are exploded.
+func GetStyle(in ParameterIn, style Style, explode *bool) (Style, bool) {
+	if style == "" {
+		switch in {
+		case ParameterInQuery, ParameterInCookie:
+			style = ParameterStyleForm
+		default:
+			style = ParameterStyleSimple
+		}
+	}
+	if explode != nil {
+		return style, *explode
+	}
+	return style, style == ParameterStyleForm || style == ParameterStyleDeepObject
+}
+
+// Returns the style and explode options in the api tag of a parameter field, like
+// `api:"style=pipeDelimited"` or `api:"style=form,explode=false"`. The style is empty
+// and explode is nil when they are not given.
+func GetParameterStyle(tag string) (style Style, explode *bool) {
+	for _, optionRaw := range splitWithEscape(tag, ",", "\\") {
+		keyValue := strings.SplitN(optionRaw, "=", 2)
+		key := strings.ToLower(strings.TrimSpace(keyValue[0]))
+		switch key {
+		case "style":
+			if len(keyValue) > 1 {
+				style = Style(strings.TrimSpace(keyValue[1]))
+			}
+		case "explode":
+			value := true
+			if len(keyValue) > 1 {
+				parsed, err := strconv.ParseBool(strings.TrimSpace(keyValue[1]))
+				if err != nil {
+					panic(fmt.Sprintf("Error parsing explode from tag: %s", keyValue[1]))
+				}
+				value = parsed
+			}
+			explode = &value
+		}
+	}
+	return
+}
+
 // A base type shared between Parameter and Header
 type ParameterBase struct {
 	// A brief description of the parameter. This could contain examples of use. CommonMark syntax MAY be used for rich text representation.
@@ -928,7 +1121,7 @@ type ParameterBase struct {
 	// Describes how the parameter value will be serialized depending on the type of the parameter value. Default values (based on value of in): for query - form; for path - simple; for header - simple; for cookie - form.
 	Style Style `json:"style,omitempty"`
 	// When this is true, parameter values of type array or object generate separate parameters for each value of the array or key-value pair of the map. For other types of parameters this property has no effect. When style is form, the default value is true. For all other styles, the default value is false.
-	Explode bool `json:"explode,omitempty"`
+	Explode *bool `json:"explode,omitempty"`
 	// Determines whether the parameter value SHOULD allow reserved characters, as defined by RFC3986 :/?#[]@!$&'()*+,;= to be included without percent-encoding. This property only applies to parameters with an in value of query. The default value is false.
 	AllowReserved bool `json:"allowReserved,omitempty"`
 	// The schema defining the type used for the parameter.
@@ -1008,6 +1201,11 @@ func (hr *Parameter) SetReference(ref string) {
 		hr.Reference = &Reference{Ref: ref}
 	}
 }
+
+// Returns the style and explode of the parameter with the defaults of its location applied.
+func (hr Parameter) GetStyle() (Style, bool) {
+	return GetStyle(hr.In, hr.Style, hr.Explode)
+}
 func (hr Parameter) GetReferencePrefix() string {
 	return "#/components/parameters/"
 }
@@ -1036,7 +1234,7 @@ type Encoding struct {
 	// Describes how a specific property value will be serialized depending on its type. See Parameter Object for details on the style property. The behavior follows the same values as query parameters, including default values. This property SHALL be ignored if the request body media type is not application/x-www-form-urlencoded.
 	Style Style `json:"style,omitempty"`
 	// When this is true, property values of type array or object generate separate parameters for each value of the array, or key-value-pair of the map. For other types of properties this property has no effect. When style is form, the default value is true. For all other styles, the default value is false. This property SHALL be ignored if the request body media type is not application/x-www-form-urlencoded.
-	Explode bool `json:"explode,omitempty"`
+	Explode *bool `json:"explode,omitempty"`
 	// Determines whether the parameter value SHOULD allow reserved characters, as defined by RFC3986 :/?#[]@!$&'()*+,;= to be included without percent-encoding. The default value is false. This property SHALL be ignored if the request body media type is not application/x-www-form-urlencoded.
 	AllowReserved bool `json:"allowReserved,omitempty"`
 }
@@ -1098,6 +1296,15 @@ func (base Response) Merge(next Response) Response {
 func (hr Response) GetReference() *Reference {
 	return hr.Reference
 }
+
+// A reference to a response is only its $ref, description is otherwise required.
+func (hr Response) MarshalJSON() ([]byte, error) {
+	if hr.Reference != nil && hr.Ref != "" {
+		return json.Marshal(hr.Reference)
+	}
+	type response Response
+	return json.Marshal(response(hr))
+}
 func (hr *Response) SetReference(ref string) {
 	if ref == "" {
 		hr.Reference = nil
@@ -1233,15 +1440,14 @@ func (bs BoolSchema) MarshalJSON() ([]byte, error) {
 }
 
 func (bs *BoolSchema) UnmarshalJSON(data []byte) error {
-	val := any(0)
-	err := json.Unmarshal(data, &val)
-	if err != nil {
-		return err
-	}
-	if b, ok := val.(bool); ok {
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
 		*bs = BoolSchema{Bool: &b}
 	} else {
-		schema := val.(Schema)
+		schema := Schema{}
+		if err := json.Unmarshal(data, &schema); err != nil {
+			return err
+		}
 		*bs = BoolSchema{Schema: &schema}
 	}
 	return nil
@@ -0,0 +1,122 @@
+package api
+
+// Visits every schema in a document including the schemas within schemas, parents
+// before their children. Schemas held by value in maps are written back after they're
+// visited so the visitor can modify any schema in place.
+type schemaWalker func(s *Schema)
+
+func (visit schemaWalker) document(doc *Document) {
+	if doc.Components != nil {
+		for name, schema := range doc.Components.Schemas {
+			visit.schema(&schema)
+			doc.Components.Schemas[name] = schema
+		}
+		for name, param := range doc.Components.Parameters {
+			visit.parameter(&param.ParameterBase)
+			doc.Components.Parameters[name] = param
+		}
+		for name, body := range doc.Components.RequestBodies {
+			visit.content(body.Content)
+			doc.Components.RequestBodies[name] = body
+		}
+		for _, response := range doc.Components.Responses {
+			visit.response(response)
+		}
+		visit.headers(doc.Components.Headers)
+		visit.callbacks(doc.Components.Callbacks)
+	}
+	visit.paths(doc.Paths)
+	visit.paths(doc.Webhooks)
+}
+
+func (visit schemaWalker) schema(s *Schema) {
+	if s == nil {
+		return
+	}
+	visit(s)
+	visit.schema(s.Items)
+	visit.schema(s.Not)
+	for i := range s.AllOf {
+		visit.schema(&s.AllOf[i])
+	}
+	for i := range s.OneOf {
+		visit.schema(&s.OneOf[i])
+	}
+	for i := range s.AnyOf {
+		visit.schema(&s.AnyOf[i])
+	}
+	visit.schemas(s.Properties)
+	visit.schemas(s.Defs)
+	if s.AdditionalProperties != nil {
+		visit.schema(s.AdditionalProperties.Schema)
+	}
+}
+
+func (visit schemaWalker) schemas(schemas map[string]Schema) {
+	for name, schema := range schemas {
+		visit.schema(&schema)
+		schemas[name] = schema
+	}
+}
+
+func (visit schemaWalker) content(content Contents) {
+	for _, media := range content {
+		if media != nil {
+			visit.schema(media.Schema)
+		}
+	}
+}
+
+func (visit schemaWalker) parameter(param *ParameterBase) {
+	visit.schema(param.Schema)
+	visit.content(param.Content)
+}
+
+func (visit schemaWalker) headers(headers Headers) {
+	for _, header := range headers {
+		if header != nil {
+			visit.parameter(&header.ParameterBase)
+		}
+	}
+}
+
+func (visit schemaWalker) response(response *Response) {
+	if response != nil {
+		visit.content(response.Content)
+		visit.headers(response.Headers)
+	}
+}
+
+func (visit schemaWalker) callbacks(callbacks Callbacks) {
+	for _, urls := range callbacks {
+		visit.paths(urls)
+	}
+}
+
+func (visit schemaWalker) paths(paths map[string]Path) {
+	for url, path := range paths {
+		visit.path(&path)
+		paths[url] = path
+	}
+}
+
+func (visit schemaWalker) path(path *Path) {
+	for i := range path.Parameters {
+		visit.parameter(&path.Parameters[i].ParameterBase)
+	}
+	for _, op := range []*Operation{path.Get, path.Put, path.Post, path.Delete, path.Options, path.Head, path.Patch, path.Trace} {
+		if op == nil {
+			continue
+		}
+		for i := range op.Parameters {
+			visit.parameter(&op.Parameters[i].ParameterBase)
+		}
+		if op.RequestBody != nil {
+			visit.content(op.RequestBody.Content)
+		}
+		for _, response := range op.Responses {
+			visit.response(response)
+		}
+		visit.callbacks(op.Callbacks)
+	}
+}
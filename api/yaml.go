@@ -0,0 +1,147 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+var _ yaml.Marshaler = Document{}
+
+// Marshals the document to YAML the same as it's marshalled to JSON, with the keys in the
+// order of the json tags and map keys sorted.
+func (doc Document) MarshalYAML() (any, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return jsonToYAMLNode(data)
+}
+
+// Marshals the document to YAML with two space indentation.
+func encodeYAML(doc Document) ([]byte, error) {
+	out := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Converts JSON to a YAML node keeping the order of the keys.
+func jsonToYAMLNode(data []byte) (*yaml.Node, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decodeYAMLNode(decoder)
+}
+
+func decodeYAMLNode(decoder *json.Decoder) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		switch value {
+		case '{':
+			node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				item, err := decodeYAMLNode(decoder)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: fmt.Sprint(key)}, item)
+			}
+			_, err = decoder.Token()
+			return node, err
+		case '[':
+			node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			for decoder.More() {
+				item, err := decodeYAMLNode(decoder)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, item)
+			}
+			_, err = decoder.Token()
+			return node, err
+		}
+		return nil, fmt.Errorf("unexpected %v in JSON", value)
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}, nil
+	case json.Number:
+		tag := "!!int"
+		if _, err := value.Int64(); err != nil {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(value)}, nil
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+	return nil, fmt.Errorf("unexpected %v in JSON", token)
+}
+
+// Converts YAML to JSON so it can be unmarshalled into the api types.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var value any
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	converted, err := yamlValueToJSON(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(converted)
+}
+
+// YAML allows keys which are not strings, JSON does not.
+func yamlValueToJSON(value any) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			converted, err := yamlValueToJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = converted
+		}
+		return v, nil
+	case map[any]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			converted, err := yamlValueToJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			out[fmt.Sprint(key)] = converted
+		}
+		return out, nil
+	case []any:
+		for i, item := range v {
+			converted, err := yamlValueToJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = converted
+		}
+		return v, nil
+	}
+	return value, nil
+}
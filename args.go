@@ -1,6 +1,7 @@
 package rez
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,6 +13,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/ClickerMonkey/deps"
 	"github.com/ClickerMonkey/rez/api"
@@ -28,6 +30,7 @@ type RequestTypes struct {
 	Path   reflect.Type
 	Query  reflect.Type
 	Header reflect.Type
+	Cookie reflect.Type
 }
 
 // A type which has one or more injectable request types. This is how the dependency injected functions
@@ -57,12 +60,20 @@ func (p Path[P]) APIValidate(op *api.Operation, v *Validator) {
 	Validate(&schema, p.Value, v.Next("path"))
 }
 func (p *Path[P]) ProvideDynamic(scope *deps.Scope) error {
-	request, _ := deps.GetScoped[http.Request](scope)
-	err := applyPathToTarget(&p.Value, request)
-	if err != nil {
-		return err
-	}
-	return ValidateInjectable(p, scope)
+	return traceInjection(scope, "path", func() error {
+		request, _ := deps.GetScoped[http.Request](scope)
+		v, _ := deps.GetScoped[Validator](scope)
+		op, _ := deps.GetScoped[api.Operation](scope)
+		err := applyParameterDefaults(op, api.ParameterInPath, &p.Value)
+		if err != nil {
+			return err
+		}
+		err = applyPathToTarget(&p.Value, request, v.Next("path"))
+		if err != nil {
+			return err
+		}
+		return ValidateInjectable(p, scope)
+	})
 }
 
 // A function parameter that is injected with query parameters.
@@ -80,12 +91,20 @@ func (q Query[Q]) APIValidate(op *api.Operation, v *Validator) {
 	Validate(&schema, q.Value, v.Next("query"))
 }
 func (q *Query[Q]) ProvideDynamic(scope *deps.Scope) error {
-	request, _ := deps.GetScoped[http.Request](scope)
-	err := applyURLToTarget(&q.Value, request)
-	if err != nil {
-		return err
-	}
-	return ValidateInjectable(q, scope)
+	return traceInjection(scope, "query", func() error {
+		request, _ := deps.GetScoped[http.Request](scope)
+		v, _ := deps.GetScoped[Validator](scope)
+		op, _ := deps.GetScoped[api.Operation](scope)
+		err := applyParameterDefaults(op, api.ParameterInQuery, &q.Value)
+		if err != nil {
+			return err
+		}
+		err = applyURLToTarget(&q.Value, request, v.Next("query"))
+		if err != nil {
+			return err
+		}
+		return ValidateInjectable(q, scope)
+	})
 }
 
 // A function parameter that is injected with the request body.
@@ -99,23 +118,28 @@ func (b Body[B]) APIRequestTypes() RequestTypes {
 	return RequestTypes{Body: deps.TypeOf[B]()}
 }
 func (b Body[B]) APIValidate(op *api.Operation, v *Validator) {
-	if op.RequestBody != nil && op.RequestBody.Content != nil && op.RequestBody.Content[api.ContentTypeJSON] != nil {
-		media := op.RequestBody.Content[api.ContentTypeJSON]
-		if media.Schema != nil {
-			Validate(media.Schema, b.Value, v.Next("body"))
-		}
+	if schema := getBodySchema(op); schema != nil {
+		Validate(schema, b.Value, v.Next("body"))
 	}
 }
 func (b *Body[B]) ProvideDynamic(scope *deps.Scope) error {
-	request, _ := deps.GetScoped[http.Request](scope)
-	router, _ := deps.GetScoped[Router](scope)
-
-	err := getBody(&b.Value, request, *router)
-	if err != nil {
-		return err
-	}
+	return traceInjection(scope, "body", func() error {
+		request, _ := deps.GetScoped[http.Request](scope)
+		router, _ := deps.GetScoped[Router](scope)
+		v, _ := deps.GetScoped[Validator](scope)
+		op, _ := deps.GetScoped[api.Operation](scope)
+
+		err := applyBodyDefaults(op, &b.Value)
+		if err != nil {
+			return err
+		}
+		err = getBody(&b.Value, request, *router, v.Next("body"))
+		if err != nil {
+			return err
+		}
 
-	return ValidateInjectable(b, scope)
+		return ValidateInjectable(b, scope)
+	})
 }
 
 // A function parameter that is injected with the body, path, and query parameters.
@@ -131,11 +155,8 @@ func (r Request[B, P, Q]) APIRequestTypes() RequestTypes {
 	return RequestTypes{Body: deps.TypeOf[B](), Path: deps.TypeOf[P](), Query: deps.TypeOf[Q]()}
 }
 func (r Request[B, P, Q]) APIValidate(op *api.Operation, v *Validator) {
-	if op.RequestBody != nil && op.RequestBody.Content != nil && op.RequestBody.Content[api.ContentTypeJSON] != nil {
-		media := op.RequestBody.Content[api.ContentTypeJSON]
-		if media.Schema != nil {
-			Validate(media.Schema, r.Body, v.Next("body"))
-		}
+	if schema := getBodySchema(op); schema != nil {
+		Validate(schema, r.Body, v.Next("body"))
 	}
 	pathSchema := op.GetParametersSchema(api.ParameterInPath)
 	Validate(&pathSchema, r.Path, v.Next("path"))
@@ -143,23 +164,39 @@ func (r Request[B, P, Q]) APIValidate(op *api.Operation, v *Validator) {
 	Validate(&querySchema, r.Query, v.Next("query"))
 }
 func (r *Request[B, P, Q]) ProvideDynamic(scope *deps.Scope) error {
-	request, _ := deps.GetScoped[http.Request](scope)
-	router, _ := deps.GetScoped[Router](scope)
-
-	err := getBody(&r.Body, request, *router)
-	if err != nil {
-		return err
-	}
-	err = applyPathToTarget(&r.Path, request)
-	if err != nil {
-		return err
-	}
-	err = applyURLToTarget(&r.Query, request)
-	if err != nil {
-		return err
-	}
+	return traceInjection(scope, "request", func() error {
+		request, _ := deps.GetScoped[http.Request](scope)
+		router, _ := deps.GetScoped[Router](scope)
+		v, _ := deps.GetScoped[Validator](scope)
+		op, _ := deps.GetScoped[api.Operation](scope)
+
+		err := applyBodyDefaults(op, &r.Body)
+		if err != nil {
+			return err
+		}
+		err = applyParameterDefaults(op, api.ParameterInPath, &r.Path)
+		if err != nil {
+			return err
+		}
+		err = applyParameterDefaults(op, api.ParameterInQuery, &r.Query)
+		if err != nil {
+			return err
+		}
+		err = getBody(&r.Body, request, *router, v.Next("body"))
+		if err != nil {
+			return err
+		}
+		err = applyPathToTarget(&r.Path, request, v.Next("path"))
+		if err != nil {
+			return err
+		}
+		err = applyURLToTarget(&r.Query, request, v.Next("query"))
+		if err != nil {
+			return err
+		}
 
-	return ValidateInjectable(r, scope)
+		return ValidateInjectable(r, scope)
+	})
 }
 
 // A function parameter that is injected with the request headers.
@@ -177,22 +214,64 @@ func (h Header[H]) APIValidate(op *api.Operation, v *Validator) {
 	Validate(&schema, h.Value, v.Next("header"))
 }
 func (h *Header[H]) ProvideDynamic(scope *deps.Scope) error {
-	request, _ := deps.GetScoped[http.Request](scope)
-	err := getHeader(&h.Value, request)
-	if err != nil {
-		return err
-	}
-	return ValidateInjectable(h, scope)
+	return traceInjection(scope, "header", func() error {
+		request, _ := deps.GetScoped[http.Request](scope)
+		v, _ := deps.GetScoped[Validator](scope)
+		op, _ := deps.GetScoped[api.Operation](scope)
+		err := applyParameterDefaults(op, api.ParameterInHeader, &h.Value)
+		if err != nil {
+			return err
+		}
+		err = getHeader(&h.Value, request, v.Next("header"))
+		if err != nil {
+			return err
+		}
+		return ValidateInjectable(h, scope)
+	})
+}
+
+// A function parameter that is injected with the request cookies.
+type Cookie[C any] struct {
+	Value C
+}
+
+var _ Injectable = &Cookie[int]{}
+
+func (c Cookie[C]) APIRequestTypes() RequestTypes {
+	return RequestTypes{Cookie: deps.TypeOf[C]()}
+}
+func (c Cookie[C]) APIValidate(op *api.Operation, v *Validator) {
+	schema := op.GetParametersSchema(api.ParameterInCookie)
+	Validate(&schema, c.Value, v.Next("cookie"))
+}
+func (c *Cookie[C]) ProvideDynamic(scope *deps.Scope) error {
+	return traceInjection(scope, "cookie", func() error {
+		request, _ := deps.GetScoped[http.Request](scope)
+		v, _ := deps.GetScoped[Validator](scope)
+		op, _ := deps.GetScoped[api.Operation](scope)
+		err := applyParameterDefaults(op, api.ParameterInCookie, &c.Value)
+		if err != nil {
+			return err
+		}
+		err = getCookie(&c.Value, request, v.Next("cookie"))
+		if err != nil {
+			return err
+		}
+		return ValidateInjectable(c, scope)
+	})
 }
 
 // Validates the injectable by pulling the validator and operation
 // off of the scope and calling APIValidate. If there are any validation
-// errors the validator (which implements error) is returned.
+// errors the validator (which implements error) is returned. Values which
+// failed to be parsed into their type are not validated again.
 func ValidateInjectable(inj Injectable, scope *deps.Scope) error {
 	v, _ := deps.GetScoped[Validator](scope)
 	op, _ := deps.GetScoped[api.Operation](scope)
 
+	validated := len(*v.Validations)
 	inj.APIValidate(op, v)
+	removeUnparsedFailures(v, validated)
 
 	if v.HasFailures() {
 		return v
@@ -200,35 +279,106 @@ func ValidateInjectable(inj Injectable, scope *deps.Scope) error {
 	return nil
 }
 
-func getHeader(header any, r *http.Request) error {
+// Returns the documented schema of the request body. All content types share the
+// same schema, JSON is preferred if it exists.
+func getBodySchema(op *api.Operation) *api.Schema {
+	if op.RequestBody == nil || op.RequestBody.Content == nil {
+		return nil
+	}
+	if media := op.RequestBody.Content[api.ContentTypeJSON]; media != nil && media.Schema != nil {
+		return media.Schema
+	}
+	for _, media := range op.RequestBody.Content {
+		if media != nil && media.Schema != nil {
+			return media.Schema
+		}
+	}
+	return nil
+}
+
+func getHeader(header any, r *http.Request, v *Validator) error {
 	outNode := &queryNode{
 		kind: queryNodeKindObject,
 	}
 
+	jt := getType(nonAnyType(header))
 	for k, v := range r.Header {
-		if len(v) > 0 {
-			outNode.get(k).set(v[0])
+		if len(v) == 0 {
+			continue
+		}
+		if field := jt.field(k); field != nil {
+			if shape := getParamShape(field.fieldType); shape != paramShapePrimitive {
+				style, explode := field.style(api.ParameterInHeader)
+				setStyledValue(outNode.get(k), k, shape, style, explode, strings.Join(v, ","))
+				continue
+			}
 		}
+		outNode.get(k).set(v[0])
 	}
 
-	outNode.fixForType(nonAnyType(header))
-	out := outNode.convert()
-	enc := applyJSONValueToTarget(header, out)
+	if err := decodeParams(outNode, header, v); err != nil {
+		return err
+	}
+	return applySentParameterDefaults(api.ParameterInHeader, header, outNode, v)
+}
+
+func getCookie(cookie any, r *http.Request, v *Validator) error {
+	outNode := &queryNode{
+		kind: queryNodeKindObject,
+	}
+
+	// Exploded arrays are sent as a cookie per item and exploded objects
+	// as a cookie per property.
+	values := url.Values{}
+	for _, c := range r.Cookies() {
+		values.Add(c.Name, c.Value)
+	}
+
+	jt := getType(nonAnyType(cookie))
+	explodedProperties := getExplodedProperties(jt, api.ParameterInCookie)
+
+	for k, v := range values {
+		if field := jt.field(k); field != nil {
+			if shape := getParamShape(field.fieldType); shape != paramShapePrimitive {
+				style, explode := field.style(api.ParameterInCookie)
+				node := outNode.get(k)
+				if shape == paramShapeArray && explode {
+					node.kind = queryNodeKindSlice
+					for i, item := range v {
+						node.index(i).set(item)
+					}
+				} else {
+					setStyledValue(node, k, shape, style, explode, v[0])
+				}
+				continue
+			}
+		} else if name, exists := explodedProperties[strings.ToLower(k)]; exists {
+			outNode.get(name).property(k).set(v[0])
+			continue
+		}
+		outNode.get(k).set(v[0])
+	}
 
-	return enc
+	if err := decodeParams(outNode, cookie, v); err != nil {
+		return err
+	}
+	return applySentParameterDefaults(api.ParameterInCookie, cookie, outNode, v)
 }
 
-func getBody(body any, r *http.Request, router Router) error {
+func getBody(body any, r *http.Request, router Router, v *Validator) error {
 	defer r.Body.Close()
 
+	if err := limitBody(r, router, v.Scope); err != nil {
+		return err
+	}
+
 	rawContentType := r.Header.Get("Content-Type")
 	contentType := api.ContentType(strings.ToLower(strings.SplitN(rawContentType, ";", 2)[0]))
 
 	var err error
+	var sent *bytes.Buffer
 
 	switch contentType {
-	case api.ContentTypeJSON, api.ContentTypeNone:
-		err = decodeJson(r.Body, body)
 	case api.ContentTypeForm:
 		err = r.ParseForm()
 		if err == nil {
@@ -240,38 +390,130 @@ func getBody(body any, r *http.Request, router Router) error {
 			err = applyMultipartFormToTarget(body, r.MultipartForm)
 		}
 	default:
-		return fmt.Errorf("Content-Type %s not supported", rawContentType)
+		decoder := router.GetDecoder(contentType)
+		if decoder == nil {
+			return UnsupportedMediaType{ContentType: rawContentType}
+		}
+		reader := io.Reader(r.Body)
+		if isJSONContentType(contentType) {
+			reader = limitJSON(reader, router.GetDecodeOptions())
+			// The body is kept to know which properties of the allocated structs weren't sent.
+			if hasStructPointer(targetValue(body).Type()) {
+				sent = &bytes.Buffer{}
+				reader = io.TeeReader(reader, sent)
+			}
+		}
+		err = decodeWith(decoder, reader, body)
 	}
 
+	if tooLarge := bodyTooLarge(err); tooLarge != nil {
+		return tooLarge
+	}
 	if err != nil && err != io.EOF {
-		return err
+		return bodyFailed(v, err)
+	}
+	if sent != nil {
+		return applySentBodyDefaults(body, sent.Bytes(), v)
 	}
 
 	return nil
 }
 
-func applyPathToTarget(target any, r *http.Request) error {
+func applyPathToTarget(target any, r *http.Request, v *Validator) error {
 	outNode := &queryNode{
 		kind: queryNodeKindObject,
 	}
 
+	jt := getType(nonAnyType(target))
 	ctx := chi.RouteContext(r.Context())
 	if ctx != nil {
 		for i, key := range ctx.URLParams.Keys {
 			value := ctx.URLParams.Values[i]
-			outNode.get(key).set(value)
+			if field := jt.field(key); field != nil {
+				style, explode := field.style(api.ParameterInPath)
+				setStyledValue(outNode.get(key), key, getParamShape(field.fieldType), style, explode, value)
+			} else {
+				outNode.get(key).set(value)
+			}
 		}
 	}
 
-	outNode.fixForType(nonAnyType(target))
-	out := outNode.convert()
-	err := applyJSONValueToTarget(target, out)
+	if err := decodeParams(outNode, target, v); err != nil {
+		return err
+	}
+	return applySentParameterDefaults(api.ParameterInPath, target, outNode, v)
+}
 
-	return err
+func applyURLToTarget(target any, r *http.Request, v *Validator) error {
+	return applyQueryToTarget(target, r.URL.Query(), v)
 }
 
-func applyURLToTarget(target any, r *http.Request) error {
-	return applyURLValuesToTarget(target, r.URL.Query())
+// Applies the query parameters to the target honoring the style of each field.
+// Fields which are deep objects or aren't parameters are parsed like form values.
+func applyQueryToTarget(target any, values url.Values, v *Validator) error {
+	outNode := &queryNode{
+		kind: queryNodeKindObject,
+	}
+
+	// The properties of exploded form objects are parameters themselves.
+	jt := getType(nonAnyType(target))
+	explodedProperties := getExplodedProperties(jt, api.ParameterInQuery)
+
+	for k, v := range values {
+		if len(v) == 0 {
+			continue
+		}
+		if field := jt.field(k); field != nil {
+			shape := getParamShape(field.fieldType)
+			style, explode := field.style(api.ParameterInQuery)
+			if style != api.ParameterStyleDeepObject && shape != paramShapePrimitive {
+				node := outNode.get(k)
+				if shape == paramShapeArray && explode {
+					node.kind = queryNodeKindSlice
+					for i, item := range v {
+						node.index(i).set(item)
+					}
+				} else {
+					setStyledValue(node, k, shape, style, explode, v[0])
+				}
+				continue
+			}
+		} else if name, exists := explodedProperties[strings.ToLower(k)]; exists {
+			outNode.get(name).property(k).set(v[0])
+			continue
+		}
+		path := urlKeySplitter.Split(strings.TrimRight(k, "]"), -1)
+		curr := outNode
+		for _, node := range path {
+			curr = curr.get(node)
+		}
+		curr.set(v[0])
+	}
+
+	if err := decodeParams(outNode, target, v); err != nil {
+		return err
+	}
+	return applySentParameterDefaults(api.ParameterInQuery, target, outNode, v)
+}
+
+// Returns the fields of exploded form objects keyed by their properties, those
+// properties are sent as parameters themselves.
+func getExplodedProperties(jt *jsonType, in api.ParameterIn) map[string]string {
+	explodedProperties := make(map[string]string)
+	if jt == nil {
+		return explodedProperties
+	}
+	for name, field := range jt.fields {
+		style, explode := field.style(in)
+		if style == api.ParameterStyleForm && explode && getParamShape(field.fieldType) == paramShapeObject {
+			if objectType := getType(getConcrete(field.fieldType)); objectType != nil {
+				for property := range objectType.fields {
+					explodedProperties[property] = name
+				}
+			}
+		}
+	}
+	return explodedProperties
 }
 
 var urlKeySplitter = regexp.MustCompile(`[\]\[\.]+`)
@@ -361,13 +603,22 @@ func applyJSONValueToTarget(target any, m any) error {
 
 // target is either *any (where value is an any to a *T) OR target is *T
 func decodeJson(reader io.Reader, target any) error {
+	return decodeWith(decodeJSON, reader, target)
+}
+
+// target is either *any (where value is an any to a *T) OR target is *T
+func decodeWith(decoder Decoder, reader io.Reader, target any) error {
 	readerTarget := target
 	rv := reflect.ValueOf(target)
 	isAny := isAnyPointer(rv)
 	if isAny {
+		// Decode into the value held so what's already set (like defaults) is kept.
+		if held := rv.Elem().Elem(); held.Kind() == reflect.Pointer && !held.IsNil() {
+			return decoder(reader, held.Interface())
+		}
 		readerTarget = reflect.New(rv.Elem().Elem().Type()).Interface()
 	}
-	err := json.NewDecoder(reader).Decode(readerTarget)
+	err := decoder(reader, readerTarget)
 	if err != nil {
 		return err
 	}
@@ -399,30 +650,40 @@ type queryNode struct {
 
 func (node *queryNode) get(x string) *queryNode {
 	if i, err := strconv.Atoi(x); err == nil {
-		node.kind = queryNodeKindSlice
-		if len(node.arr) <= i {
-			arr := make([]*queryNode, i+1)
-			copy(arr, node.arr)
-			node.arr = arr
-		}
-		n := node.arr[i]
-		if n == nil {
-			n = &queryNode{}
-			node.arr[i] = n
-		}
-		return n
+		return node.index(i)
 	} else {
-		node.kind = queryNodeKindObject
-		if node.obj == nil {
-			node.obj = map[string]*queryNode{}
-		}
-		n := node.obj[x]
-		if n == nil {
-			n = &queryNode{}
-			node.obj[x] = n
-		}
-		return n
+		return node.property(x)
+	}
+}
+
+// Returns the item at the index, making this node a slice.
+func (node *queryNode) index(i int) *queryNode {
+	node.kind = queryNodeKindSlice
+	if len(node.arr) <= i {
+		arr := make([]*queryNode, i+1)
+		copy(arr, node.arr)
+		node.arr = arr
+	}
+	n := node.arr[i]
+	if n == nil {
+		n = &queryNode{}
+		node.arr[i] = n
+	}
+	return n
+}
+
+// Returns the property with the key, making this node an object.
+func (node *queryNode) property(x string) *queryNode {
+	node.kind = queryNodeKindObject
+	if node.obj == nil {
+		node.obj = map[string]*queryNode{}
+	}
+	n := node.obj[x]
+	if n == nil {
+		n = &queryNode{}
+		node.obj[x] = n
 	}
+	return n
 }
 
 func (node *queryNode) set(value any) {
@@ -441,6 +702,11 @@ func (node *queryNode) fixForType(typ reflect.Type) {
 			}
 		}
 	case queryNodeKindObject:
+		if typ.Kind() == reflect.Map {
+			for _, v := range node.obj {
+				v.fixForType(typ.Elem())
+			}
+		}
 		jt := getType(typ)
 		if jt != nil {
 			for k, v := range node.obj {
@@ -462,6 +728,18 @@ func (node *queryNode) fixForType(typ reflect.Type) {
 	}
 }
 
+// Returns what was sent in the node, where objects are maps of their properties.
+func (node *queryNode) sent() any {
+	if node.kind != queryNodeKindObject {
+		return node.value
+	}
+	sent := make(map[string]any, len(node.obj))
+	for key, value := range node.obj {
+		sent[key] = value.sent()
+	}
+	return sent
+}
+
 func (node *queryNode) convert() any {
 	switch node.kind {
 	case queryNodeKindSlice:
@@ -563,7 +841,7 @@ func parseType(t reflect.Type, s string) (any, error) {
 	return nil, ErrUnsupportedType
 }
 
-var jsonTypes map[reflect.Type]*jsonType = make(map[reflect.Type]*jsonType)
+var jsonTypes sync.Map
 
 type jsonType struct {
 	fields map[string]*jsonField
@@ -572,12 +850,12 @@ type jsonType struct {
 type jsonField struct {
 	fieldType reflect.Type
 	indices   []int
+	tag       reflect.StructTag
 }
 
 func getType(typ reflect.Type) *jsonType {
-	jt := jsonTypes[typ]
-	if jt != nil {
-		return jt
+	if jt, ok := jsonTypes.Load(typ); ok {
+		return jt.(*jsonType)
 	}
 	for typ.Kind() == reflect.Pointer {
 		typ = typ.Elem()
@@ -585,10 +863,9 @@ func getType(typ reflect.Type) *jsonType {
 	if typ.Kind() != reflect.Struct {
 		return nil
 	}
-	jt = &jsonType{
+	jt := &jsonType{
 		fields: make(map[string]*jsonField),
 	}
-	jsonTypes[typ] = jt
 
 	var iterateFields func(st reflect.Type, indices []int)
 
@@ -615,11 +892,13 @@ func getType(typ reflect.Type) *jsonType {
 				jt.fields[key] = &jsonField{
 					fieldType: field.Type,
 					indices:   fieldIndices,
+					tag:       field.Tag,
 				}
 			}
 		}
 	}
 	iterateFields(typ, []int{})
 
-	return jt
+	stored, _ := jsonTypes.LoadOrStore(typ, jt)
+	return stored.(*jsonType)
 }
@@ -0,0 +1,172 @@
+// Command rez generates code from the OpenAPI document of a rez site.
+//
+//	rez client -in http://localhost:3000/doc/openapi3.json -package taskclient -out taskclient/client.go
+//	rez scaffold -in openapi.yaml -package tasks -out tasks/handlers.go
+//	rez diff -old openapi.v1.json -new http://localhost:3000/doc/openapi3.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ClickerMonkey/rez/api"
+	"github.com/ClickerMonkey/rez/gen"
+)
+
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+var commands = []command{
+	{"client", "generates a typed Go client from an OpenAPI document", runClient},
+	{"scaffold", "generates types and stub rez handlers from an OpenAPI document", runScaffold},
+	{"diff", "lists the changes between two OpenAPI documents and fails on breaking changes", runDiff},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "rez %s: %v\n", cmd.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: rez <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.description)
+	}
+}
+
+func runClient(args []string) error {
+	flags := flag.NewFlagSet("client", flag.ContinueOnError)
+	in := flags.String("in", "openapi3.json", "the file or URL of the OpenAPI document")
+	out := flags.String("out", "", "the file to write the client to, defaults to stdout")
+	pkg := flags.String("package", "client", "the package name of the client")
+	name := flags.String("name", "Client", "the type name of the client")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	doc, err := readDocument(*in)
+	if err != nil {
+		return err
+	}
+
+	source, err := gen.Client(doc, gen.ClientOptions{Package: *pkg, Name: *name})
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(*out, source)
+}
+
+func runScaffold(args []string) error {
+	flags := flag.NewFlagSet("scaffold", flag.ContinueOnError)
+	in := flags.String("in", "openapi3.json", "the file or URL of the OpenAPI document")
+	out := flags.String("out", "", "the file to write the handlers to, defaults to stdout")
+	pkg := flags.String("package", "handlers", "the package name of the handlers")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	doc, err := readDocument(*in)
+	if err != nil {
+		return err
+	}
+
+	source, err := gen.Scaffold(doc, gen.ScaffoldOptions{Package: *pkg})
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(*out, source)
+}
+
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	previous := flags.String("old", "", "the file or URL of the previous OpenAPI document")
+	current := flags.String("new", "openapi3.json", "the file or URL of the current OpenAPI document")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *previous == "" {
+		return fmt.Errorf("-old is required")
+	}
+
+	before, err := readDocument(*previous)
+	if err != nil {
+		return err
+	}
+	after, err := readDocument(*current)
+	if err != nil {
+		return err
+	}
+
+	changes := api.Diff(*before, *after)
+	if len(changes) > 0 {
+		fmt.Println(changes.String())
+	}
+	if breaking := changes.Breaking(); len(breaking) > 0 {
+		return fmt.Errorf("%d breaking changes", len(breaking))
+	}
+	return nil
+}
+
+// Reads the OpenAPI document in JSON or YAML from a file or URL.
+func readDocument(in string) (*api.Document, error) {
+	var data []byte
+	var err error
+
+	if strings.HasPrefix(in, "http://") || strings.HasPrefix(in, "https://") {
+		res, err := http.Get(in)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s returned %s", in, res.Status)
+		}
+		data, err = io.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		data, err = os.ReadFile(in)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	doc, err := api.ParseDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid OpenAPI document: %w", in, err)
+	}
+	return doc, nil
+}
+
+func writeOutput(out string, data []byte) error {
+	if out == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(out, data, 0644)
+}
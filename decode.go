@@ -0,0 +1,476 @@
+package rez
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ClickerMonkey/rez/api"
+)
+
+// Sets a value to the value of a parameter node. Values which can't be parsed are added
+// to the validator as type failures with the path, the returned error is for types which
+// can't be parameters at all.
+type paramDecoder func(node *queryNode, value reflect.Value, v *Validator, path []string) error
+
+// The decoders compiled for each type.
+var paramDecoders sync.Map
+
+// Decodes the parameters in the object node into the target, which is a pointer to the value
+// or a pointer to an interface holding a pointer to the value. The validator has the path
+// of the parameters' location.
+func decodeParams(node *queryNode, target any, v *Validator) error {
+	value := targetValue(target)
+
+	return getParamDecoder(value.Type())(node, value, v, make([]string, 0, 4))
+}
+
+// Returns the value the target points to. The target is a pointer to the value or a pointer
+// to an interface holding a pointer to the value.
+func targetValue(target any) reflect.Value {
+	value := reflect.ValueOf(target).Elem()
+	if value.Kind() == reflect.Interface && !value.IsNil() {
+		value = value.Elem()
+		if value.Kind() == reflect.Pointer {
+			value = value.Elem()
+		}
+	}
+	return value
+}
+
+// Adds a type failure for the parameter at the path.
+func paramFailed(v *Validator, path []string, message string) {
+	fullPath := make([]string, 0, len(v.Path)+len(path))
+	fullPath = append(fullPath, v.Path...)
+	fullPath = append(fullPath, path...)
+
+	v.Add(Validation{
+		Path:    fullPath,
+		Rule:    ValidationRuleType,
+		Message: message,
+	})
+}
+
+// Returns the decoder for the type, compiling it the first time it's needed.
+func getParamDecoder(typ reflect.Type) paramDecoder {
+	if decoder, ok := paramDecoders.Load(typ); ok {
+		return decoder.(paramDecoder)
+	}
+
+	// Recursive types use the decoder before it's compiled.
+	var compiled paramDecoder
+	var wait sync.WaitGroup
+	wait.Add(1)
+	indirect, loaded := paramDecoders.LoadOrStore(typ, paramDecoder(func(node *queryNode, value reflect.Value, v *Validator, path []string) error {
+		wait.Wait()
+		return compiled(node, value, v, path)
+	}))
+	if loaded {
+		return indirect.(paramDecoder)
+	}
+
+	compiled = compileParamDecoder(typ)
+	wait.Done()
+	paramDecoders.Store(typ, compiled)
+
+	return compiled
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+func compileParamDecoder(typ reflect.Type) paramDecoder {
+	if typ.Kind() != reflect.Pointer && typ.Kind() != reflect.Interface {
+		if reflect.PointerTo(typ).Implements(textUnmarshalerType) {
+			return decodeParamText
+		}
+		if reflect.PointerTo(typ).Implements(jsonUnmarshalerType) {
+			return decodeParamJSON
+		}
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return decodeParamPrimitive(func(s string, value reflect.Value) error {
+			value.SetString(s)
+			return nil
+		}, "")
+	case reflect.Bool:
+		return decodeParamPrimitive(func(s string, value reflect.Value) error {
+			parsed, err := strconv.ParseBool(s)
+			value.SetBool(parsed)
+			return err
+		}, "boolean")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return decodeParamPrimitive(func(s string, value reflect.Value) error {
+			parsed, err := strconv.ParseInt(s, 10, typ.Bits())
+			value.SetInt(parsed)
+			return err
+		}, "integer")
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return decodeParamPrimitive(func(s string, value reflect.Value) error {
+			parsed, err := strconv.ParseUint(s, 10, typ.Bits())
+			value.SetUint(parsed)
+			return err
+		}, "unsigned integer")
+	case reflect.Float32, reflect.Float64:
+		return decodeParamPrimitive(func(s string, value reflect.Value) error {
+			parsed, err := strconv.ParseFloat(s, typ.Bits())
+			value.SetFloat(parsed)
+			return err
+		}, "number")
+	case reflect.Complex64, reflect.Complex128:
+		return decodeParamPrimitive(func(s string, value reflect.Value) error {
+			parsed, err := strconv.ParseComplex(s, typ.Bits())
+			value.SetComplex(parsed)
+			return err
+		}, "complex number")
+	case reflect.Pointer:
+		return compilePointerDecoder(typ)
+	case reflect.Interface:
+		if typ.NumMethod() == 0 {
+			return func(node *queryNode, value reflect.Value, v *Validator, path []string) error {
+				if converted := node.convert(); converted != nil {
+					value.Set(reflect.ValueOf(converted))
+				}
+				return nil
+			}
+		}
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return decodeParamPrimitive(func(s string, value reflect.Value) error {
+				value.SetBytes([]byte(s))
+				return nil
+			}, "")
+		}
+		return compileSliceDecoder(typ)
+	case reflect.Array:
+		return compileArrayDecoder(typ)
+	case reflect.Map:
+		if typ.Key().Kind() == reflect.String {
+			return compileMapDecoder(typ)
+		}
+	case reflect.Struct:
+		return compileStructDecoder(typ)
+	}
+
+	return func(node *queryNode, value reflect.Value, v *Validator, path []string) error {
+		return fmt.Errorf("%w: %v can't be a parameter", ErrUnsupportedType, typ)
+	}
+}
+
+// Returns a decoder of a single value which is parsed by parse. The description of the type is used in failures.
+func decodeParamPrimitive(parse func(s string, value reflect.Value) error, description string) paramDecoder {
+	return func(node *queryNode, value reflect.Value, v *Validator, path []string) error {
+		s, ok := node.single(v, path)
+		if ok && parse(s, value) != nil {
+			paramFailed(v, path, fmt.Sprintf("%q is not a valid %s", s, description))
+		}
+		return nil
+	}
+}
+
+// Decodes a value which implements encoding.TextUnmarshaler.
+func decodeParamText(node *queryNode, value reflect.Value, v *Validator, path []string) error {
+	s, ok := node.single(v, path)
+	if !ok {
+		return nil
+	}
+	if err := value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+		paramFailed(v, path, fmt.Sprintf("%q is not valid: %v", s, err))
+	}
+	return nil
+}
+
+// Decodes a value which implements json.Unmarshaler by giving it the node as JSON.
+func decodeParamJSON(node *queryNode, value reflect.Value, v *Validator, path []string) error {
+	data, err := json.Marshal(node.convert())
+	if err != nil {
+		return err
+	}
+	if err := value.Addr().Interface().(json.Unmarshaler).UnmarshalJSON(data); err != nil {
+		paramFailed(v, path, fmt.Sprintf("%s is not valid: %v", data, err))
+	}
+	return nil
+}
+
+// Pointers are nil when the value is empty.
+func compilePointerDecoder(typ reflect.Type) paramDecoder {
+	elem := getParamDecoder(typ.Elem())
+
+	return func(node *queryNode, value reflect.Value, v *Validator, path []string) error {
+		if node.kind == queryNodeKindValue && toString(node.value) == "" {
+			value.Set(reflect.Zero(typ))
+			return nil
+		}
+		if value.IsNil() {
+			value.Set(reflect.New(typ.Elem()))
+		}
+		return elem(node, value.Elem(), v, path)
+	}
+}
+
+// Slices are decoded from the indexed values of the node or a comma separated value.
+func compileSliceDecoder(typ reflect.Type) paramDecoder {
+	elem := getParamDecoder(typ.Elem())
+
+	return func(node *queryNode, value reflect.Value, v *Validator, path []string) error {
+		items, ok := node.items(v, path)
+		if !ok {
+			return nil
+		}
+		slice := reflect.MakeSlice(typ, len(items), len(items))
+		for i, item := range items {
+			if item == nil {
+				continue
+			}
+			if err := elem(item, slice.Index(i), v, append(path, strconv.Itoa(i))); err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+		return nil
+	}
+}
+
+// Arrays are decoded like slices, ignoring the values past the end of the array.
+func compileArrayDecoder(typ reflect.Type) paramDecoder {
+	elem := getParamDecoder(typ.Elem())
+
+	return func(node *queryNode, value reflect.Value, v *Validator, path []string) error {
+		items, ok := node.items(v, path)
+		if !ok {
+			return nil
+		}
+		for i, item := range items {
+			if i >= typ.Len() {
+				break
+			}
+			if item == nil {
+				continue
+			}
+			if err := elem(item, value.Index(i), v, append(path, strconv.Itoa(i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func compileMapDecoder(typ reflect.Type) paramDecoder {
+	elem := getParamDecoder(typ.Elem())
+
+	return func(node *queryNode, value reflect.Value, v *Validator, path []string) error {
+		if !node.expectObject(v, path) {
+			return nil
+		}
+		if value.IsNil() {
+			value.Set(reflect.MakeMapWithSize(typ, len(node.obj)))
+		}
+		for key, property := range node.obj {
+			item := reflect.New(typ.Elem()).Elem()
+			if err := elem(property, item, v, append(path, key)); err != nil {
+				return err
+			}
+			value.SetMapIndex(reflect.ValueOf(key).Convert(typ.Key()), item)
+		}
+		return nil
+	}
+}
+
+// A field of a struct set by a struct decoder.
+type paramField struct {
+	property string
+	indices  []int
+	decoder  paramDecoder
+}
+
+// Structs have their fields set by the lowercase name of their json property,
+// the keys of the node which aren't fields are ignored.
+func compileStructDecoder(typ reflect.Type) paramDecoder {
+	fields := make(map[string]*paramField)
+
+	var addFields func(st reflect.Type, indices []int)
+	addFields = func(st reflect.Type, indices []int) {
+		for i := 0; i < st.NumField(); i++ {
+			field := st.Field(i)
+			property, _, skip := api.GetJSONOptions(field)
+			fieldIndices := append(append([]int{}, indices...), i)
+			if field.Anonymous && getConcrete(field.Type).Kind() == reflect.Struct {
+				if field.Tag.Get("json") == "" && (field.IsExported() || field.Type.Kind() != reflect.Pointer) {
+					addFields(getConcrete(field.Type), fieldIndices)
+					continue
+				}
+			}
+			if skip {
+				continue
+			}
+			key := strings.ToLower(property)
+			if _, exists := fields[key]; !exists || len(fieldIndices) < len(fields[key].indices) {
+				fields[key] = &paramField{property: property, indices: fieldIndices}
+			}
+		}
+	}
+	addFields(typ, []int{})
+
+	for _, field := range fields {
+		field.decoder = getParamDecoder(typ.FieldByIndex(field.indices).Type)
+	}
+
+	return func(node *queryNode, value reflect.Value, v *Validator, path []string) error {
+		if !node.expectObject(v, path) {
+			return nil
+		}
+		for key, property := range node.obj {
+			field := fields[strings.ToLower(key)]
+			if field == nil {
+				continue
+			}
+			if err := field.decoder(property, fieldByIndex(value, field.indices), v, append(path, field.property)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// Returns the field of the struct with the indices, allocating embedded struct pointers along the way.
+func fieldByIndex(value reflect.Value, indices []int) reflect.Value {
+	for i, index := range indices {
+		if i > 0 && value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+		value = value.Field(index)
+	}
+	return value
+}
+
+// Returns the value of the node, which must not be an array or object.
+func (node *queryNode) single(v *Validator, path []string) (string, bool) {
+	switch node.kind {
+	case queryNodeKindSlice:
+		paramFailed(v, path, "must be a single value and not a list")
+		return "", false
+	case queryNodeKindObject:
+		paramFailed(v, path, "must be a single value and not an object")
+		return "", false
+	case queryNodeKindUnspecified:
+		return "", true
+	}
+	return toString(node.value), true
+}
+
+// Returns the items of the node, a single value is split by commas.
+func (node *queryNode) items(v *Validator, path []string) ([]*queryNode, bool) {
+	switch node.kind {
+	case queryNodeKindSlice:
+		return node.arr, true
+	case queryNodeKindObject:
+		paramFailed(v, path, "must be a list and not an object")
+		return nil, false
+	case queryNodeKindValue:
+		s := toString(node.value)
+		if s == "" {
+			return []*queryNode{}, true
+		}
+		parts := strings.Split(s, ",")
+		items := make([]*queryNode, len(parts))
+		for i, part := range parts {
+			items[i] = &queryNode{kind: queryNodeKindValue, value: part}
+		}
+		return items, true
+	}
+	return []*queryNode{}, true
+}
+
+// Returns whether the node is an object, adding a failure if it isn't.
+func (node *queryNode) expectObject(v *Validator, path []string) bool {
+	switch node.kind {
+	case queryNodeKindSlice:
+		paramFailed(v, path, "must be an object and not a list")
+		return false
+	case queryNodeKindValue:
+		paramFailed(v, path, fmt.Sprintf("%q must be an object and not a single value", toString(node.value)))
+		return false
+	}
+	return true
+}
+
+// Adds a failure for an error decoding the body if the body couldn't be parsed into its type,
+// otherwise the error is returned. Errors like failing to read the body are not failures of the request.
+func bodyFailed(v *Validator, err error) error {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	var xmlSyntaxErr *xml.SyntaxError
+	var limitErr jsonLimitError
+
+	switch {
+	case errors.As(err, &typeErr):
+		path := []string{}
+		if typeErr.Field != "" {
+			path = strings.Split(typeErr.Field, ".")
+		}
+		paramFailed(v, path, fmt.Sprintf("%s is not a valid %v", typeErr.Value, typeErr.Type))
+	case errors.As(err, &syntaxErr), errors.As(err, &xmlSyntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		paramFailed(v, nil, err.Error())
+	case errors.As(err, &limitErr):
+		v.Add(Validation{Path: v.Path, Rule: limitErr.rule, Message: limitErr.message})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		v.Add(Validation{Path: v.Path, Rule: ValidationRuleUnknownField, Message: strings.TrimPrefix(err.Error(), "json: ")})
+	default:
+		return err
+	}
+	return nil
+}
+
+// Removes the failures added after the given index which are for values that couldn't be
+// parsed. The value isn't what was sent, so failures of its schema are misleading.
+func removeUnparsedFailures(v *Validator, from int) {
+	validations := *v.Validations
+	unparsed := make([][]string, 0)
+	for _, validation := range validations[:from] {
+		if validation.Rule == ValidationRuleType {
+			unparsed = append(unparsed, validation.Path)
+		}
+	}
+	if len(unparsed) == 0 {
+		return
+	}
+
+	kept := validations[:from]
+	for _, validation := range validations[from:] {
+		if !hasPathPrefix(validation.Path, unparsed) {
+			kept = append(kept, validation)
+		}
+	}
+	*v.Validations = kept
+}
+
+// Returns whether the path starts with any of the prefixes.
+func hasPathPrefix(path []string, prefixes [][]string) bool {
+	for _, prefix := range prefixes {
+		if len(prefix) > len(path) {
+			continue
+		}
+		matches := true
+		for i := range prefix {
+			if prefix[i] != path[i] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
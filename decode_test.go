@@ -0,0 +1,214 @@
+package rez
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type decodeID struct {
+	prefix string
+	number int
+}
+
+func (id *decodeID) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(strings.Replace(string(text), "-", " ", 1), "%s %d", &id.prefix, &id.number)
+	return err
+}
+
+type decodeUUID [16]byte
+
+func (id *decodeUUID) UnmarshalText(text []byte) error {
+	if hex.DecodedLen(len(text)) != len(id) {
+		return fmt.Errorf("%q is not a uuid", text)
+	}
+	_, err := hex.Decode(id[:], text)
+	return err
+}
+
+type decodeLookup struct {
+	ID decodeUUID `json:"id"`
+}
+
+type decodeTree struct {
+	Name  string      `json:"name"`
+	Child *decodeTree `json:"child,omitempty"`
+}
+
+type decodePaging struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+type decodeQuery struct {
+	decodePaging
+	IDs    []int      `json:"ids" api:"explode=false"`
+	Since  time.Time  `json:"since"`
+	Filter styleRange `json:"filter" api:"style=deepObject"`
+	Max    *float64   `json:"max"`
+	Tree   decodeTree `json:"tree" api:"style=deepObject"`
+	Flags  [2]bool    `json:"flags"`
+}
+
+type decodePath struct {
+	ID decodeID `json:"id"`
+}
+
+type decodeHeader struct {
+	Count uint8 `json:"X-Count"`
+}
+
+type decodeCookie struct {
+	Session int `json:"session"`
+}
+
+type decodeBody struct {
+	Name  string `json:"name" api:"maxlength=5"`
+	Count int    `json:"count"`
+}
+
+type decodeSearch struct {
+	Page  int `json:"page" api:"min=1"`
+	Limit int `json:"limit" api:"max=100"`
+}
+
+func TestDecodeParams(t *testing.T) {
+	site := New(chi.NewRouter())
+
+	var path decodePath
+	site.Get("/path/{id}", func(p Path[decodePath]) {
+		path = p.Value
+	})
+	var query decodeQuery
+	site.Get("/query", func(q Query[decodeQuery]) {
+		query = q.Value
+	})
+	var lookup decodeLookup
+	site.Get("/lookup", func(q Query[decodeLookup]) {
+		lookup = q.Value
+	})
+	site.Get("/header", func(h Header[decodeHeader]) {})
+	site.Get("/cookie", func(c Cookie[decodeCookie]) {})
+	site.Group(func(r Router) {
+		r.EnableValidation(true)
+		r.Post("/search/{id}", func(r Request[decodeBody, decodePath, decodeSearch]) {})
+	})
+
+	serve := func(request *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		site.router.ServeHTTP(w, request)
+		return w
+	}
+
+	w := serve(httptest.NewRequest(http.MethodGet, "/path/task-12", nil))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, decodeID{prefix: "task", number: 12}, path.ID)
+
+	w = serve(httptest.NewRequest(http.MethodGet, "/query?limit=10&offset=20&ids=1,2&since=2023-01-02T03:04:05Z&filter[min]=1&filter[max]=2&max=&tree[name]=a&tree[child][name]=b&flags=true&flags=false&flags=true", nil))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, decodeQuery{
+		decodePaging: decodePaging{Limit: 10, Offset: 20},
+		IDs:          []int{1, 2},
+		Since:        time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Filter:       styleRange{Min: 1, Max: 2},
+		Tree:         decodeTree{Name: "a", Child: &decodeTree{Name: "b"}},
+		Flags:        [2]bool{true, false},
+	}, query)
+
+	w = serve(httptest.NewRequest(http.MethodGet, "/lookup?id=00112233445566778899aabbccddeeff", nil))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, decodeUUID{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}, lookup.ID)
+
+	doc := site.Open.Build()
+	params := doc.Paths["/lookup"].Get.Parameters
+	if assert.Len(t, params, 1) {
+		schema, _ := json.Marshal(params[0].Schema.ResolveReference())
+		assert.Equal(t, `{"type":"string"}`, string(schema))
+	}
+
+	w = serve(httptest.NewRequest(http.MethodGet, "/query?max=1.5", nil))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotNil(t, query.Max)
+	assert.Equal(t, 1.5, *query.Max)
+
+	tests := []struct {
+		name    string
+		request *http.Request
+		error   string
+	}{
+		{
+			name:    "path text",
+			request: httptest.NewRequest(http.MethodGet, "/path/12", nil),
+			error:   `{"validations":[{"path":["path","id"],"rule":"type","message":"\"12\" is not valid: EOF"}]}`,
+		},
+		{
+			name:    "query list item",
+			request: httptest.NewRequest(http.MethodGet, "/query?ids=1,x", nil),
+			error:   `{"validations":[{"path":["query","ids","1"],"rule":"type","message":"\"x\" is not a valid integer"}]}`,
+		},
+		{
+			name:    "query deep object",
+			request: httptest.NewRequest(http.MethodGet, "/query?filter[min]=abc", nil),
+			error:   `{"validations":[{"path":["query","filter","min"],"rule":"type","message":"\"abc\" is not a valid integer"}]}`,
+		},
+		{
+			name:    "query embedded",
+			request: httptest.NewRequest(http.MethodGet, "/query?limit=ten", nil),
+			error:   `{"validations":[{"path":["query","limit"],"rule":"type","message":"\"ten\" is not a valid integer"}]}`,
+		},
+		{
+			name:    "query object",
+			request: httptest.NewRequest(http.MethodGet, "/query?limit[a]=1", nil),
+			error:   `{"validations":[{"path":["query","limit"],"rule":"type","message":"must be a single value and not an object"}]}`,
+		},
+		{
+			name:    "query time",
+			request: httptest.NewRequest(http.MethodGet, "/query?since=yesterday", nil),
+			error:   `{"validations":[{"path":["query","since"],"rule":"type","message":"\"yesterday\" is not valid: parsing time \"yesterday\" as \"2006-01-02T15:04:05Z07:00\": cannot parse \"yesterday\" as \"2006\""}]}`,
+		},
+		{
+			name: "header",
+			request: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/header", nil)
+				r.Header.Set("X-Count", "300")
+				return r
+			}(),
+			error: `{"validations":[{"path":["header","X-Count"],"rule":"type","message":"\"300\" is not a valid unsigned integer"}]}`,
+		},
+		{
+			name: "cookie",
+			request: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/cookie", nil)
+				r.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+				return r
+			}(),
+			error: `{"validations":[{"path":["cookie","session"],"rule":"type","message":"\"abc\" is not a valid integer"}]}`,
+		},
+		{
+			name:    "everything",
+			request: httptest.NewRequest(http.MethodPost, "/search/12?page=first&limit=500", strings.NewReader(`{"name":"abcdefg","count":"many"}`)),
+			error:   `{"validations":[{"path":["body","count"],"rule":"type","message":"string is not a valid int"},{"path":["path","id"],"rule":"type","message":"\"12\" is not valid: EOF"},{"path":["query","page"],"rule":"type","message":"\"first\" is not a valid integer"},{"path":["body","name"],"rule":"maxLength","message":"7 exceeds the maximum length of 5"},{"path":["query","limit"],"rule":"maximum","message":"500 exceeds the maximum of 100"}]}`,
+		},
+		{
+			name:    "body syntax",
+			request: httptest.NewRequest(http.MethodPost, "/search/a-1?page=1", strings.NewReader(`{"name":`)),
+			error:   `{"validations":[{"path":["body"],"rule":"type","message":"unexpected EOF"}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(test.request)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, test.error, strings.TrimSpace(w.Body.String()))
+		})
+	}
+}
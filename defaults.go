@@ -0,0 +1,239 @@
+package rez
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/ClickerMonkey/deps"
+	"github.com/ClickerMonkey/rez/api"
+)
+
+// Sets the defaults of the parameters in the location to the target.
+func applyParameterDefaults(op *api.Operation, in api.ParameterIn, target any) error {
+	schema := op.GetParametersSchema(in)
+	return applyDefaults(&schema, target)
+}
+
+// Sets the defaults of the request body to the target.
+func applyBodyDefaults(op *api.Operation, target any) error {
+	if schema := getBodySchema(op); schema != nil {
+		return applyDefaults(schema, target)
+	}
+	return nil
+}
+
+// Sets the properties of the target which have a default in the schema. This is done before
+// the request is parsed into the target so only the values which aren't sent keep their default.
+// The target is a pointer to the value or a pointer to an interface holding a pointer to the value.
+func applyDefaults(schema *api.Schema, target any) error {
+	return setDefaults(schema, targetValue(target))
+}
+
+// Sets the defaults of the properties of the schema to the struct value. The properties of
+// nested structs are set unless the property has a default itself. Nil pointers are left alone
+// and get their defaults if the decoder allocates them (see applySentDefaults).
+func setDefaults(schema *api.Schema, value reflect.Value) error {
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	properties := getSchemaProperties(schema)
+	if len(properties) == 0 {
+		return nil
+	}
+
+	jt := getType(value.Type())
+	for property, propertySchema := range properties {
+		field := jt.field(property)
+		if field == nil {
+			continue
+		}
+		fieldValue := fieldByIndex(value, field.indices)
+		if defaultValue := getSchemaDefault(&propertySchema); defaultValue != nil {
+			if err := setDefault(fieldValue, *defaultValue); err != nil {
+				return fmt.Errorf("default of %s: %w", property, err)
+			}
+		} else if err := setDefaults(&propertySchema, fieldValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sets the defaults of the parameters in the location which weren't sent to the structs the
+// decoder allocated for pointers, which were nil when the defaults were applied.
+func applySentParameterDefaults(in api.ParameterIn, target any, node *queryNode, v *Validator) error {
+	if !hasStructPointer(targetValue(target).Type()) {
+		return nil
+	}
+	op, _ := deps.GetScoped[api.Operation](v.Scope)
+	if op == nil {
+		return nil
+	}
+	schema := op.GetParametersSchema(in)
+	return applySentDefaults(&schema, target, node.sent())
+}
+
+// Sets the defaults of the request body which weren't sent to the structs the decoder
+// allocated for pointers. The data is the JSON body that was decoded into the target.
+func applySentBodyDefaults(target any, data []byte, v *Validator) error {
+	op, _ := deps.GetScoped[api.Operation](v.Scope)
+	if op == nil {
+		return nil
+	}
+	schema := getBodySchema(op)
+	if schema == nil {
+		return nil
+	}
+	var sent any
+	if err := json.Unmarshal(data, &sent); err != nil {
+		return nil
+	}
+	return applySentDefaults(schema, target, sent)
+}
+
+// Sets the defaults of the properties which weren't sent to the structs the decoder allocated
+// for pointers. The sent value is what the request had for the target, where objects are maps.
+func applySentDefaults(schema *api.Schema, target any, sent any) error {
+	return setSentDefaults(schema, targetValue(target), sent, false)
+}
+
+// Sets the defaults of the properties which weren't sent when the struct value was allocated
+// by the decoder, and looks for allocated structs in the properties which were sent.
+func setSentDefaults(schema *api.Schema, value reflect.Value, sent any, allocated bool) error {
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+		allocated = true
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	properties := getSchemaProperties(schema)
+	if len(properties) == 0 {
+		return nil
+	}
+
+	// The decoders match properties without case.
+	object, _ := sent.(map[string]any)
+	sentProperties := make(map[string]any, len(object))
+	for key, propertyValue := range object {
+		sentProperties[strings.ToLower(key)] = propertyValue
+	}
+
+	jt := getType(value.Type())
+	for property, propertySchema := range properties {
+		field := jt.field(property)
+		if field == nil {
+			continue
+		}
+		propertySent, isSent := sentProperties[strings.ToLower(property)]
+		if !isSent && !allocated {
+			continue
+		}
+		fieldValue := fieldByIndex(value, field.indices)
+		if isSent {
+			if err := setSentDefaults(&propertySchema, fieldValue, propertySent, allocated); err != nil {
+				return err
+			}
+		} else if defaultValue := getSchemaDefault(&propertySchema); defaultValue != nil {
+			if err := setDefault(fieldValue, *defaultValue); err != nil {
+				return fmt.Errorf("default of %s: %w", property, err)
+			}
+		} else if err := setDefaults(&propertySchema, fieldValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var structPointers sync.Map
+
+// Returns whether the type is or has an exported field which is a pointer to a struct,
+// which is only allocated when the request has a value for it.
+func hasStructPointer(typ reflect.Type) bool {
+	if has, ok := structPointers.Load(typ); ok {
+		return has.(bool)
+	}
+	has := findStructPointer(typ, make(map[reflect.Type]bool))
+	structPointers.Store(typ, has)
+	return has
+}
+
+func findStructPointer(typ reflect.Type, visited map[reflect.Type]bool) bool {
+	if typ.Kind() == reflect.Pointer {
+		return typ.Elem().Kind() == reflect.Struct
+	}
+	if typ.Kind() != reflect.Struct || visited[typ] {
+		return false
+	}
+	visited[typ] = true
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if (field.IsExported() || field.Anonymous) && findStructPointer(field.Type, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sets the value to a copy of the default, which is a JSON value or a value of the same type.
+func setDefault(value reflect.Value, defaultValue any) error {
+	if defaultValue == nil {
+		return nil
+	}
+	data, err := json.Marshal(defaultValue)
+	if err != nil {
+		return err
+	}
+	parsed := reflect.New(value.Type())
+	if err := json.Unmarshal(data, parsed.Interface()); err != nil {
+		return err
+	}
+	value.Set(parsed.Elem())
+	return nil
+}
+
+// Returns the default of the schema or the schema it wraps, if any.
+func getSchemaDefault(schema *api.Schema) *any {
+	for s := schema; s != nil; s = getWrappedSchema(s) {
+		if s.Default != nil {
+			return s.Default
+		}
+	}
+	return nil
+}
+
+// Returns the properties of the schema or the schema it wraps, if any.
+func getSchemaProperties(schema *api.Schema) map[string]api.Schema {
+	for s := schema; s != nil; s = getWrappedSchema(s) {
+		if len(s.Properties) > 0 {
+			return s.Properties
+		}
+	}
+	return nil
+}
+
+// Returns the schema that a schema refers to, is all of, or makes nullable.
+func getWrappedSchema(s *api.Schema) *api.Schema {
+	if resolved := s.ResolveReference(); resolved != s {
+		return resolved
+	}
+	if len(s.AllOf) == 1 {
+		return &s.AllOf[0]
+	}
+	if len(s.OneOf) == 2 && s.OneOf[1].Type == api.DataTypeNull {
+		return &s.OneOf[0]
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package rez
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ClickerMonkey/rez/api"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type defaultSize int
+
+func (defaultSize) APIBaseSchema() *api.Schema {
+	var size any = 50
+	return &api.Schema{Default: &size}
+}
+
+type defaultRange struct {
+	Min int `json:"min" api:"default=1,min=1"`
+	Max int `json:"max" api:"default=10"`
+}
+
+type defaultQuery struct {
+	Limit  int           `json:"limit" api:"default=20,min=0"`
+	Sort   string        `json:"sort" api:"default=name"`
+	Desc   *bool         `json:"desc" api:"default=true"`
+	Size   defaultSize   `json:"size"`
+	Range  defaultRange  `json:"range" api:"style=deepObject"`
+	Page   *defaultRange `json:"page" api:"style=deepObject"`
+	Offset int           `json:"offset"`
+}
+
+type defaultOptions struct {
+	Notify bool     `json:"notify" api:"default=true"`
+	Tags   []string `json:"tags" api:"default=[\"new\"]"`
+}
+
+type defaultBody struct {
+	Name     string          `json:"name" api:"default=Untitled"`
+	Priority float64         `json:"priority" api:"default=0.5"`
+	Options  defaultOptions  `json:"options"`
+	Extra    *defaultOptions `json:"extra,omitempty"`
+}
+
+func TestDefaults(t *testing.T) {
+	site := New(chi.NewRouter())
+
+	var query defaultQuery
+	site.Group(func(r Router) {
+		r.EnableValidation(true)
+		r.Get("/query", func(q Query[defaultQuery]) {
+			query = q.Value
+		})
+	})
+	var body defaultBody
+	site.Post("/body", func(b Body[defaultBody]) {
+		body = b.Value
+	})
+
+	serve := func(request *http.Request) {
+		w := httptest.NewRecorder()
+		site.router.ServeHTTP(w, request)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	yes, no := true, false
+
+	serve(httptest.NewRequest(http.MethodGet, "/query", nil))
+	assert.Equal(t, defaultQuery{
+		Limit: 20,
+		Sort:  "name",
+		Desc:  &yes,
+		Size:  50,
+		Range: defaultRange{Min: 1, Max: 10},
+	}, query)
+
+	serve(httptest.NewRequest(http.MethodGet, "/query?limit=0&desc=false&size=5&range[max]=3&page[max]=4&offset=2", nil))
+	assert.Equal(t, defaultQuery{
+		Limit:  0,
+		Sort:   "name",
+		Desc:   &no,
+		Size:   5,
+		Range:  defaultRange{Min: 1, Max: 3},
+		Page:   &defaultRange{Min: 1, Max: 4},
+		Offset: 2,
+	}, query)
+
+	serve(httptest.NewRequest(http.MethodPost, "/body", strings.NewReader(`{"options":{"notify":false}}`)))
+	assert.Equal(t, defaultBody{
+		Name:     "Untitled",
+		Priority: 0.5,
+		Options:  defaultOptions{Notify: false, Tags: []string{"new"}},
+	}, body)
+
+	serve(httptest.NewRequest(http.MethodPost, "/body", strings.NewReader(`{"name":"Task","extra":{"tags":[]}}`)))
+	assert.Equal(t, defaultBody{
+		Name:     "Task",
+		Priority: 0.5,
+		Options:  defaultOptions{Notify: true, Tags: []string{"new"}},
+		Extra:    &defaultOptions{Notify: true, Tags: []string{}},
+	}, body)
+
+	doc := site.Open.Build()
+
+	defaults := make(map[string]any)
+	for _, param := range doc.Paths["/query"].Get.Parameters {
+		if param.Schema.Default != nil {
+			defaults[param.Name] = *param.Schema.Default
+		}
+	}
+	assert.Equal(t, map[string]any{
+		"limit": int64(20),
+		"sort":  "name",
+		"desc":  true,
+	}, defaults)
+}
@@ -0,0 +1,217 @@
+package rez
+
+import (
+	"embed"
+	"encoding/json"
+	"html"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/ClickerMonkey/rez/api"
+	"github.com/go-chi/chi/v5"
+)
+
+// The version of Swagger UI served by ServeSwaggerUIEmbedded.
+const SwaggerUIVersion = "5.18.2"
+
+//go:embed assets/swagger-ui
+var swaggerUIFiles embed.FS
+
+// The embedded Swagger UI assets (swagger-ui-bundle.js and swagger-ui.css).
+var SwaggerUIAssets, _ = fs.Sub(swaggerUIFiles, "assets/swagger-ui")
+
+// The file in the assets given to ServeRedocFS which has the Redoc standalone bundle.
+const RedocBundleFile = "redoc.standalone.js"
+
+// The version of Redoc served by ServeRedocEmbedded.
+const RedocVersion = "2.1.5"
+
+//go:generate curl -fsSL -o assets/redoc/redoc.standalone.js https://unpkg.com/redoc@2.1.5/bundles/redoc.standalone.js
+//go:embed assets/redoc
+var redocFiles embed.FS
+
+// The embedded Redoc assets (redoc.standalone.js).
+var RedocAssets, _ = fs.Sub(redocFiles, "assets/redoc")
+
+// Serves Swagger UI at the given pattern without loading anything from a CDN. The
+// embedded Swagger UI assets are served under the pattern with a path containing
+// SwaggerUIVersion so they can be cached indefinitely, and the page has no inline
+// scripts or styles so it can be served with a strict Content-Security-Policy.
+func (site *Site) ServeSwaggerUIEmbedded(pattern string, options map[string]any) {
+	site.ensureServeOpenJSON(pattern)
+
+	optionsJson := site.swaggerUIOptions(options)
+	title, description := site.docsInfo()
+	base := site.url + pattern
+	assets := base + "/" + SwaggerUIVersion
+
+	page := []byte(`<!DOCTYPE html>
+<html lang="en">
+	<head>
+		<meta charset="utf-8" />
+		<meta name="viewport" content="width=device-width, initial-scale=1" />
+		<meta name="description" content="` + html.EscapeString(description) + `" />
+		<title>` + html.EscapeString(title) + `</title>
+		<link rel="stylesheet" href="` + assets + `/swagger-ui.css" />
+	</head>
+	<body>
+		<div id="ui-wrapper-new">Loading....</div>
+		<script src="` + assets + `/swagger-ui-bundle.js"></script>
+		<script src="` + base + `/swagger-initializer.js"></script>
+	</body>
+</html>`)
+
+	initializer := []byte(`window.onload = function() {
+	var swaggerUIOptions = ` + string(optionsJson) + `;
+	if (!swaggerUIOptions.presets) {
+		swaggerUIOptions.presets = [
+			SwaggerUIBundle.presets.apis
+		];
+	}
+	if (!swaggerUIOptions.plugins) {
+		swaggerUIOptions.plugins = [
+			SwaggerUIBundle.plugins.DownloadUrl
+		];
+	}
+	window.ui = SwaggerUIBundle(swaggerUIOptions);
+};
+`)
+
+	site.router.Get(pattern, site.serveDocsFile(page, api.ContentTypeHTML))
+	site.router.Get(pattern+"/swagger-initializer.js", site.serveDocsFile(initializer, contentTypeJavaScript))
+	site.serveDocsAssets(pattern+"/"+SwaggerUIVersion, SwaggerUIAssets, cacheControlImmutable)
+}
+
+// Serves Redoc at the given pattern without loading anything from a CDN. The embedded
+// Redoc bundle is served under the pattern with a path containing RedocVersion so it can
+// be cached indefinitely. No fonts are loaded from Google Fonts, the fonts used by Redoc
+// can be given in the options. This panics if the bundle was not downloaded with go generate.
+func (site *Site) ServeRedocEmbedded(pattern string, options map[string]any) {
+	if _, err := fs.Stat(RedocAssets, RedocBundleFile); err != nil {
+		panic("rez: the Redoc bundle is not embedded, run go generate in github.com/ClickerMonkey/rez")
+	}
+	site.serveRedoc(pattern, "/"+RedocVersion, RedocAssets, cacheControlImmutable, options)
+}
+
+// Serves Redoc at the given pattern without loading anything from a CDN. The assets must
+// contain the Redoc standalone bundle (RedocBundleFile), which is served under the pattern
+// along with any other files in the assets. No fonts are loaded from Google Fonts, the
+// fonts used by Redoc can be given in the options or included in the assets.
+//
+//	//go:embed redoc
+//	var redoc embed.FS
+//	assets, _ := fs.Sub(redoc, "redoc")
+//	site.ServeRedocFS("/doc/redoc", assets, nil)
+func (site *Site) ServeRedocFS(pattern string, assets fs.FS, options map[string]any) {
+	site.serveRedoc(pattern, "/assets", assets, cacheControlAssets, options)
+}
+
+// Serves the Redoc page at the pattern with the bundle in the assets served under the assets path.
+func (site *Site) serveRedoc(pattern string, assetsPath string, assets fs.FS, cacheControl string, options map[string]any) {
+	site.ensureServeOpenJSON(pattern)
+
+	if options == nil {
+		options = make(map[string]any)
+	}
+	optionsJson, _ := json.Marshal(options)
+	specJson, _ := json.Marshal(site.openJsonPath)
+	title, description := site.docsInfo()
+	base := site.url + pattern
+
+	page := []byte(`<!DOCTYPE html>
+<html>
+	<head>
+		<meta charset="utf-8" />
+		<meta name="viewport" content="width=device-width, initial-scale=1" />
+		<meta name="description" content="` + html.EscapeString(description) + `" />
+		<title>` + html.EscapeString(title) + `</title>
+	</head>
+	<body>
+		<div id="redoc-container"></div>
+		<script src="` + base + assetsPath + `/` + RedocBundleFile + `"></script>
+		<script src="` + base + `/redoc-initializer.js"></script>
+	</body>
+</html>`)
+
+	initializer := []byte(`document.body.style.margin = '0';
+Redoc.init(
+	` + string(specJson) + `,
+	` + string(optionsJson) + `,
+	document.getElementById('redoc-container')
+);
+`)
+
+	site.router.Get(pattern, site.serveDocsFile(page, api.ContentTypeHTML))
+	site.router.Get(pattern+"/redoc-initializer.js", site.serveDocsFile(initializer, contentTypeJavaScript))
+	site.serveDocsAssets(pattern+assetsPath, assets, cacheControl)
+}
+
+const contentTypeJavaScript api.ContentType = "text/javascript; charset=utf-8"
+
+// Assets with a version in their path never change.
+const cacheControlImmutable = "public, max-age=31536000, immutable"
+
+// Assets given by the user can change between deployments.
+const cacheControlAssets = "public, max-age=3600"
+
+// Returns a handler which sends the generated documentation file. These depend
+// on the site's configuration so the client must revalidate them.
+func (site *Site) serveDocsFile(data []byte, contentType api.ContentType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		site.SendAny(data, w, http.StatusOK, contentType)
+	}
+}
+
+// Serves the files in assets under the given pattern with the given Cache-Control header.
+func (site *Site) serveDocsAssets(pattern string, assets fs.FS, cacheControl string) {
+	prefix := site.url + pattern + "/"
+	files := http.StripPrefix(prefix, http.FileServer(http.FS(assets)))
+
+	site.router.Get(pattern+"/*", func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "*")
+		if name == "" || strings.HasSuffix(name, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Cache-Control", cacheControl)
+		files.ServeHTTP(w, r)
+	})
+}
+
+// Returns the options for Swagger UI with the defaults applied.
+func (site *Site) swaggerUIOptions(options map[string]any) []byte {
+	if options == nil {
+		options = make(map[string]any)
+	}
+	options["url"] = site.openJsonPath
+	options["dom_id"] = "#ui-wrapper-new"
+	if _, exists := options["docExpansion"]; !exists {
+		options["docExpansion"] = "list"
+	}
+	if _, exists := options["deepLinking"]; !exists {
+		options["deepLinking"] = true
+	}
+	if _, exists := options["filter"]; !exists {
+		options["filter"] = true
+	}
+	optionsJson, _ := json.Marshal(options)
+	return optionsJson
+}
+
+// Returns the title and description of the documentation pages.
+func (site *Site) docsInfo() (title string, description string) {
+	title = "Docs"
+	description = "Rest API Documentation"
+
+	if site.Open != nil {
+		if site.Open.Document.Info.Title != "" {
+			title = site.Open.Document.Info.Title
+		}
+		if site.Open.Document.Info.Description != "" {
+			description = site.Open.Document.Info.Description
+		}
+	}
+	return
+}
@@ -0,0 +1,88 @@
+package rez
+
+import (
+	"io/fs"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeSwaggerUIEmbedded(t *testing.T) {
+	site := New(chi.NewRouter())
+	site.Route("/doc", func(r Router) {
+		r.(*Site).ServeSwaggerUIEmbedded("/swagger", nil)
+	})
+
+	get := func(url string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		site.Chi().ServeHTTP(res, httptest.NewRequest("GET", url, nil))
+		return res
+	}
+
+	page := get("/doc/swagger")
+	assert.Equal(t, 200, page.Code)
+	assert.Equal(t, "no-cache", page.Header().Get("Cache-Control"))
+	assert.Contains(t, page.Body.String(), `src="/doc/swagger/`+SwaggerUIVersion+`/swagger-ui-bundle.js"`)
+	assert.NotContains(t, page.Body.String(), "unpkg.com")
+
+	initializer := get("/doc/swagger/swagger-initializer.js")
+	assert.Equal(t, 200, initializer.Code)
+	assert.Contains(t, initializer.Body.String(), `"url":"/doc/openapi3.json"`)
+
+	for _, file := range []string{"swagger-ui-bundle.js", "swagger-ui.css"} {
+		asset := get("/doc/swagger/" + SwaggerUIVersion + "/" + file)
+		assert.Equal(t, 200, asset.Code, file)
+		assert.Equal(t, cacheControlImmutable, asset.Header().Get("Cache-Control"), file)
+		assert.Greater(t, asset.Body.Len(), 100000, file)
+	}
+
+	assert.Equal(t, 404, get("/doc/swagger/"+SwaggerUIVersion+"/").Code)
+	assert.Equal(t, 404, get("/doc/swagger/"+SwaggerUIVersion+"/missing.js").Code)
+}
+
+func TestServeRedocEmbedded(t *testing.T) {
+	site := New(chi.NewRouter())
+	if _, err := fs.Stat(RedocAssets, RedocBundleFile); err != nil {
+		assert.Panics(t, func() {
+			site.ServeRedocEmbedded("/redoc", nil)
+		})
+		t.Skip("the Redoc bundle is not downloaded, run go generate")
+	}
+	site.ServeRedocEmbedded("/redoc", nil)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		site.Chi().ServeHTTP(res, httptest.NewRequest("GET", url, nil))
+		return res
+	}
+
+	page := get("/redoc")
+	assert.Equal(t, 200, page.Code)
+	assert.Contains(t, page.Body.String(), `src="/redoc/`+RedocVersion+`/`+RedocBundleFile+`"`)
+	assert.NotContains(t, page.Body.String(), "googleapis")
+
+	bundle := get("/redoc/" + RedocVersion + "/" + RedocBundleFile)
+	assert.Equal(t, 200, bundle.Code)
+	assert.Equal(t, cacheControlImmutable, bundle.Header().Get("Cache-Control"))
+	assert.Greater(t, bundle.Body.Len(), 100000)
+}
+
+func TestServeRedocFS(t *testing.T) {
+	site := New(chi.NewRouter())
+	site.ServeRedocFS("/redoc", fstest.MapFS{
+		RedocBundleFile: &fstest.MapFile{Data: []byte("var Redoc = {};")},
+	}, nil)
+
+	res := httptest.NewRecorder()
+	site.Chi().ServeHTTP(res, httptest.NewRequest("GET", "/redoc", nil))
+	assert.Equal(t, 200, res.Code)
+	assert.NotContains(t, res.Body.String(), "googleapis")
+
+	res = httptest.NewRecorder()
+	site.Chi().ServeHTTP(res, httptest.NewRequest("GET", "/redoc/assets/"+RedocBundleFile, nil))
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "var Redoc = {};", res.Body.String())
+}
@@ -12,7 +12,7 @@ import (
 )
 
 type SearchQuery struct {
-	Limit  int `json:"limit"`
+	Limit  int `json:"limit" api:"default=20"`
 	Offset int `json:"offset"`
 }
 
@@ -29,15 +29,6 @@ func (q SearchQuery) FullValidate(v *rez.Validator) {
 	}
 }
 
-func (q SearchQuery) Resolve() (limit int, offset int) {
-	limit = q.Limit
-	offset = q.Offset
-	if limit <= 0 {
-		limit = 20
-	}
-	return
-}
-
 type TaskPath struct {
 	ID int `json:"id" api:"min=1"`
 }
@@ -137,12 +128,10 @@ func deleteTask(params TaskPath) *rez.NotFound[string] {
 	return nil
 }
 func searchTask(body TaskSearchRequest, query SearchQuery) (*TaskSearchResponse, *rez.Unauthorized[string]) {
-	_, offset := query.Resolve()
-
 	return &TaskSearchResponse{
 		Total:   1,
 		Results: []Task{{Name: "REZ Examples", Done: false}},
-		Offset:  offset,
+		Offset:  query.Offset,
 	}, nil
 }
 func authLogin(body AuthRequest) (*AuthResult, *rez.Unauthorized[string]) {
@@ -129,6 +129,11 @@ func (f *File[FD]) ProvideDynamic(scope *deps.Scope) error {
 	var err error
 
 	request, _ := deps.GetScoped[http.Request](scope)
+	router, _ := deps.GetScoped[Router](scope)
+
+	if err = limitBody(request, *router, scope); err != nil {
+		return err
+	}
 	f.ReadCloser = request.Body
 
 	if cd := request.Header.Get("Content-Disposition"); cd != "" {
@@ -295,31 +300,37 @@ func (f Files[FD]) IsParsed() bool {
 
 // When this is injected, the file is the entire body
 func (f *Files[FD]) ProvideDynamic(scope *deps.Scope) error {
-	var err error
+	return traceInjection(scope, "files", func() error {
+		var err error
 
-	request, _ := deps.GetScoped[http.Request](scope)
-	router, _ := deps.GetScoped[Router](scope)
+		request, _ := deps.GetScoped[http.Request](scope)
+		router, _ := deps.GetScoped[Router](scope)
 
-	err = request.ParseMultipartForm((*router).GetMemoryLimit())
-	if err != nil {
-		return nil
-	}
+		err = limitBody(request, *router, scope)
+		if err != nil {
+			return err
+		}
+		err = request.ParseMultipartForm((*router).GetMemoryLimit())
+		if err != nil {
+			return bodyTooLarge(err)
+		}
 
-	for formKey, fileHeaders := range request.MultipartForm.File {
-		for fileIndex := range fileHeaders {
-			file := File[FD]{
-				formKey:   formKey,
-				fileIndex: fileIndex,
-			}
-			err = file.Parse(request)
-			if err != nil {
-				return err
+		for formKey, fileHeaders := range request.MultipartForm.File {
+			for fileIndex := range fileHeaders {
+				file := File[FD]{
+					formKey:   formKey,
+					fileIndex: fileIndex,
+				}
+				err = file.Parse(request)
+				if err != nil {
+					return err
+				}
+				*f = append(*f, file)
 			}
-			*f = append(*f, file)
 		}
-	}
 
-	return err
+		return err
+	})
 }
 
 func getFormFileInfo(data []byte) (formKey string, fileCount int, err error) {
@@ -0,0 +1,1164 @@
+// Package gen generates Go source code from an OpenAPI document, either one built
+// by a rez.Site (site.BuildDocument()) or one read from the openapi3.json it serves.
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ClickerMonkey/rez/api"
+)
+
+// Options for generating a client.
+type ClientOptions struct {
+	// The package name of the generated client, defaults to "client".
+	Package string
+	// The name of the generated client type, defaults to "Client".
+	// The constructor is named New followed by this name.
+	Name string
+}
+
+// Generates a typed Go client for the operations in the document. Each operation
+// is a method on the client named after its OperationID, or its method and path
+// when it doesn't have one. Path, query, header, and cookie parameters and the
+// request body are fields on a params type, and each documented status has a
+// field on a result type named after its status (OK, NotFound, BadRequest, etc).
+//
+//	doc := site.BuildDocument()
+//	source, err := gen.Client(doc, gen.ClientOptions{Package: "taskclient"})
+func Client(doc *api.Document, options ClientOptions) ([]byte, error) {
+	if options.Package == "" {
+		options.Package = "client"
+	}
+	if options.Name == "" {
+		options.Name = "Client"
+	}
+
+	g := newGenerator(doc)
+	g.shareShapes = true
+	for _, imp := range clientImports {
+		g.imports[imp] = true
+	}
+	g.reserve(options.Name)
+	g.reserve("New" + options.Name)
+	g.reserve("StatusError")
+
+	operations := g.operations()
+	methods := &bytes.Buffer{}
+	for _, op := range operations {
+		g.writeOperation(methods, options.Name, op)
+	}
+
+	out := &bytes.Buffer{}
+	out.WriteString("// Code generated by github.com/ClickerMonkey/rez/gen. DO NOT EDIT.\n\n")
+	writeComment(out, "", fmt.Sprintf("Package %s is a client for %s.", options.Package, g.title()))
+	fmt.Fprintf(out, "package %s\n\n", options.Package)
+	out.WriteString("import (\n")
+	for _, imp := range g.importList() {
+		fmt.Fprintf(out, "\t%q\n", imp)
+	}
+	out.WriteString(")\n\n")
+	out.WriteString(strings.ReplaceAll(clientRuntime, "$Client", options.Name))
+	out.Write(g.types.Bytes())
+	out.Write(methods.Bytes())
+
+	source, err := format.Source(out.Bytes())
+	if err != nil {
+		return out.Bytes(), fmt.Errorf("generated client is invalid: %w", err)
+	}
+	return source, nil
+}
+
+// An operation in the document and where it is.
+type operation struct {
+	method string
+	path   string
+	name   string
+	op     *api.Operation
+	params []api.Parameter
+}
+
+// A parameter or body field on a params type.
+type paramField struct {
+	name  string
+	param api.Parameter
+	typ   string
+}
+
+// A documented status on a result type.
+type resultField struct {
+	name   string
+	status string
+	typ    string
+}
+
+type generator struct {
+	doc     *api.Document
+	types   bytes.Buffer
+	imports map[string]bool
+	// go type names which are taken
+	used map[string]bool
+	// component schema name to go type name
+	named map[string]string
+	// json of component schemas to the shortest name of a component with the same json,
+	// so inline schemas can use the type of an identical component
+	shapes map[string]string
+	// if identical components (like Task and CreatedTask) share a type
+	shareShapes bool
+}
+
+func newGenerator(doc *api.Document) *generator {
+	g := &generator{
+		doc:     doc,
+		imports: make(map[string]bool),
+		used:    make(map[string]bool),
+		named:   make(map[string]string),
+		shapes:  make(map[string]string),
+	}
+
+	if doc.Components != nil {
+		for name, schema := range doc.Components.Schemas {
+			if !isStruct(&schema) {
+				continue
+			}
+			data, err := json.Marshal(schema)
+			if err != nil {
+				continue
+			}
+			shape := string(data)
+			if existing, exists := g.shapes[shape]; !exists || shorterName(name, existing) {
+				g.shapes[shape] = name
+			}
+		}
+	}
+
+	return g
+}
+
+func (g *generator) title() string {
+	if g.doc.Info.Title != "" {
+		return g.doc.Info.Title
+	}
+	return "the API"
+}
+
+func (g *generator) importList() []string {
+	list := make([]string, 0, len(g.imports))
+	for imp := range g.imports {
+		list = append(list, imp)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Marks the go type name as taken.
+func (g *generator) reserve(name string) {
+	g.used[name] = true
+}
+
+// Returns a go type name based on the given name which isn't taken yet, and takes it.
+func (g *generator) unique(name string) string {
+	candidate := name
+	for i := 2; g.used[candidate]; i++ {
+		candidate = name + strconv.Itoa(i)
+	}
+	g.used[candidate] = true
+	return candidate
+}
+
+var methodOrder = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Returns all operations in the document sorted by path and method and with unique names.
+func (g *generator) operations() []operation {
+	urls := make([]string, 0, len(g.doc.Paths))
+	for url := range g.doc.Paths {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	operations := make([]operation, 0)
+	for _, url := range urls {
+		path := g.doc.Paths[url]
+		ops := []*api.Operation{path.Get, path.Put, path.Post, path.Delete, path.Options, path.Head, path.Patch, path.Trace}
+		for i, op := range ops {
+			if op == nil {
+				continue
+			}
+			method := methodOrder[i]
+			name := exportName(op.OperationID)
+			if op.OperationID == "" {
+				name = operationName(method, url)
+			}
+			operations = append(operations, operation{
+				method: strings.ToUpper(method),
+				path:   url,
+				name:   g.unique(name),
+				op:     op,
+				params: g.parameters(path.Parameters, op.Parameters),
+			})
+		}
+	}
+	return operations
+}
+
+// Returns the path parameters with the operation parameters overriding them.
+func (g *generator) parameters(pathParams []api.Parameter, opParams []api.Parameter) []api.Parameter {
+	params := make([]api.Parameter, 0, len(pathParams)+len(opParams))
+	for _, param := range append(append([]api.Parameter{}, pathParams...), opParams...) {
+		param = g.resolveParameter(param)
+		replaced := false
+		for i := range params {
+			if !params[i].IsUnique(param) {
+				params[i] = param
+				replaced = true
+			}
+		}
+		if !replaced {
+			params = append(params, param)
+		}
+	}
+	order := map[api.ParameterIn]int{
+		api.ParameterInPath:   0,
+		api.ParameterInQuery:  1,
+		api.ParameterInHeader: 2,
+		api.ParameterInCookie: 3,
+	}
+	sort.SliceStable(params, func(i, j int) bool {
+		if params[i].In != params[j].In {
+			return order[params[i].In] < order[params[j].In]
+		}
+		return params[i].Name < params[j].Name
+	})
+	return params
+}
+
+func (g *generator) resolveParameter(param api.Parameter) api.Parameter {
+	if param.Reference == nil || g.doc.Components == nil {
+		return param
+	}
+	name := strings.TrimPrefix(param.Ref, param.GetReferencePrefix())
+	if resolved, ok := g.doc.Components.Parameters[name]; ok {
+		return resolved
+	}
+	return param
+}
+
+func (g *generator) resolveRequestBody(body *api.RequestBody) *api.RequestBody {
+	if body == nil || body.Reference == nil || g.doc.Components == nil {
+		return body
+	}
+	name := strings.TrimPrefix(body.Ref, body.GetReferencePrefix())
+	if resolved, ok := g.doc.Components.RequestBodies[name]; ok {
+		return &resolved
+	}
+	return body
+}
+
+func (g *generator) resolveResponse(response *api.Response) *api.Response {
+	if response == nil || response.Reference == nil || g.doc.Components == nil {
+		return response
+	}
+	name := strings.TrimPrefix(response.Ref, response.GetReferencePrefix())
+	if resolved, ok := g.doc.Components.Responses[name]; ok && resolved != nil {
+		return resolved
+	}
+	return response
+}
+
+// Writes the params type, result type, and method for the operation.
+func (g *generator) writeOperation(out *bytes.Buffer, client string, op operation) {
+	paramsName := ""
+	fields := make([]paramField, 0, len(op.params))
+	taken := map[string]bool{}
+	for _, param := range op.params {
+		typ := "any"
+		if param.Schema != nil {
+			typ = g.goType(param.Schema, op.name+exportName(param.Name))
+		}
+		if !param.Required && param.In != api.ParameterInPath {
+			typ = optionalType(typ)
+		}
+		fields = append(fields, paramField{name: uniqueField(taken, exportName(param.Name)), param: param, typ: typ})
+	}
+
+	body := g.resolveRequestBody(op.op.RequestBody)
+	bodyField, bodyType, bodyContentType := "", "", ""
+	if body != nil && len(body.Content) > 0 {
+		bodyField = uniqueField(taken, "Body")
+		if contentType, media := jsonContent(body.Content); media != nil {
+			bodyType = g.goType(media.Schema, op.name+"Body")
+			if !body.Required {
+				bodyType = optionalType(bodyType)
+			}
+			bodyContentType = string(contentType)
+		} else {
+			bodyType = "io.Reader"
+			bodyContentType = string(sortedContentTypes(body.Content)[0])
+		}
+	}
+
+	if len(fields) > 0 || bodyField != "" {
+		paramsName = g.unique(op.name + "Params")
+		writeComment(&g.types, "", fmt.Sprintf("The parameters for %s.", op.name))
+		fmt.Fprintf(&g.types, "type %s struct {\n", paramsName)
+		for _, field := range fields {
+			comment := fmt.Sprintf("The %s parameter %q.", field.param.In, field.param.Name)
+			if field.param.Description != "" {
+				comment += " " + field.param.Description
+			}
+			writeComment(&g.types, "\t", comment)
+			fmt.Fprintf(&g.types, "\t%s %s\n", field.name, field.typ)
+		}
+		if bodyField != "" {
+			comment := fmt.Sprintf("The request body, sent as %s.", bodyContentType)
+			if body.Description != "" {
+				comment += " " + body.Description
+			}
+			writeComment(&g.types, "\t", comment)
+			fmt.Fprintf(&g.types, "\t%s %s\n", bodyField, bodyType)
+			if bodyType == "io.Reader" {
+				writeComment(&g.types, "\t", fmt.Sprintf("The content type of the request body, defaults to %s.", bodyContentType))
+				fmt.Fprintf(&g.types, "\t%sContentType string\n", bodyField)
+			}
+		}
+		g.types.WriteString("}\n\n")
+	}
+
+	results := g.resultFields(op)
+	resultName := g.unique(op.name + "Result")
+	writeComment(&g.types, "", fmt.Sprintf("The response of %s. The field for the returned status is set.", op.name))
+	fmt.Fprintf(&g.types, "type %s struct {\n", resultName)
+	g.types.WriteString("\t// The status code of the response.\n\tStatus int\n")
+	g.types.WriteString("\t// The headers of the response.\n\tHeader http.Header\n")
+	for _, result := range results {
+		comment := fmt.Sprintf("Set when the status is %s.", result.status)
+		if text := statusText(result.status); text != "" {
+			comment = fmt.Sprintf("Set when the status is %s (%s).", result.status, text)
+		}
+		writeComment(&g.types, "\t", comment)
+		fmt.Fprintf(&g.types, "\t%s %s\n", result.name, result.typ)
+	}
+	g.types.WriteString("}\n\n")
+
+	comment := op.op.Summary
+	if op.op.Description != "" {
+		comment = strings.TrimSpace(comment + "\n\n" + op.op.Description)
+	}
+	if comment == "" {
+		comment = fmt.Sprintf("Sends %s %s.", op.method, op.path)
+	} else {
+		comment += "\n\n" + fmt.Sprintf("%s %s", op.method, op.path)
+	}
+	if op.op.Deprecated {
+		comment += "\n\nDeprecated: this operation is deprecated."
+	}
+	writeComment(out, "", comment)
+	if paramsName != "" {
+		fmt.Fprintf(out, "func (c *%s) %s(ctx context.Context, params %s) (*%s, error) {\n", client, op.name, paramsName, resultName)
+	} else {
+		fmt.Fprintf(out, "func (c *%s) %s(ctx context.Context) (*%s, error) {\n", client, op.name, resultName)
+	}
+	fmt.Fprintf(out, "\treq := request{method: %q, path: %s}\n", op.method, pathExpression(op.path, fields))
+	for _, field := range fields {
+		switch field.param.In {
+		case api.ParameterInQuery:
+			style, explode := api.GetStyle(field.param.In, field.param.Style, field.param.Explode)
+			fmt.Fprintf(out, "\treq.setQuery(%q, %q, %t, params.%s)\n", field.param.Name, style, explode, field.name)
+		case api.ParameterInHeader:
+			_, explode := api.GetStyle(field.param.In, field.param.Style, field.param.Explode)
+			fmt.Fprintf(out, "\treq.setHeader(%q, %t, params.%s)\n", field.param.Name, explode, field.name)
+		case api.ParameterInCookie:
+			_, explode := api.GetStyle(field.param.In, field.param.Style, field.param.Explode)
+			fmt.Fprintf(out, "\treq.setCookie(%q, %t, params.%s)\n", field.param.Name, explode, field.name)
+		}
+	}
+	if bodyField != "" {
+		if bodyType == "io.Reader" {
+			fmt.Fprintf(out, "\treq.body, req.contentType = params.%s, params.%sContentType\n", bodyField, bodyField)
+			fmt.Fprintf(out, "\tif req.contentType == \"\" {\n\t\treq.contentType = %q\n\t}\n", bodyContentType)
+		} else {
+			fmt.Fprintf(out, "\tif err := req.setJSON(%q, params.%s); err != nil {\n\t\treturn nil, err\n\t}\n", bodyContentType, bodyField)
+		}
+	}
+	out.WriteString("\tres, data, err := c.send(ctx, req)\n")
+	out.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	fmt.Fprintf(out, "\tresult := &%s{Status: res.StatusCode, Header: res.Header}\n", resultName)
+
+	defaultResult := (*resultField)(nil)
+	out.WriteString("\tswitch {\n")
+	for i, result := range results {
+		if result.status == "default" {
+			defaultResult = &results[i]
+			continue
+		}
+		fmt.Fprintf(out, "\tcase %s:\n", statusCondition(result.status))
+		writeDecode(out, result)
+	}
+	out.WriteString("\tdefault:\n")
+	if defaultResult != nil {
+		writeDecode(out, *defaultResult)
+	} else {
+		out.WriteString("\t\tif res.StatusCode >= 300 {\n\t\t\terr = &StatusError{Status: res.StatusCode, Header: res.Header, Body: data}\n\t\t}\n")
+	}
+	out.WriteString("\t}\n")
+	out.WriteString("\treturn result, err\n}\n\n")
+}
+
+func writeDecode(out *bytes.Buffer, result resultField) {
+	if result.typ == "" {
+		return
+	}
+	fmt.Fprintf(out, "\t\terr = decode(res, data, &result.%s)\n", result.name)
+}
+
+// Returns the fields of the result type, one for each documented status.
+func (g *generator) resultFields(op operation) []resultField {
+	statuses := make([]string, 0, len(op.op.Responses))
+	for status := range op.op.Responses {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i] == "default" || statuses[j] == "default" {
+			return statuses[j] == "default" && statuses[i] != "default"
+		}
+		return statuses[i] < statuses[j]
+	})
+
+	taken := map[string]bool{"Status": true, "Header": true}
+	fields := make([]resultField, 0, len(statuses))
+	for _, status := range statuses {
+		response := g.resolveResponse(op.op.Responses[status])
+		name := uniqueField(taken, statusName(status))
+		field := resultField{name: name, status: status}
+		if response != nil && len(response.Content) > 0 {
+			if _, media := jsonContent(response.Content); media != nil {
+				field.typ = optionalType(g.goType(media.Schema, op.name+name))
+			} else if textContent(response.Content) {
+				field.typ = "*string"
+			} else {
+				field.typ = "[]byte"
+			}
+		}
+		if field.typ != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Returns the go type for the schema. Named schemas and objects are declared
+// as types, the name hint is used for objects which don't have a name.
+func (g *generator) goType(s *api.Schema, hint string) string {
+	if s == nil {
+		return "any"
+	}
+	if s.Reference != nil && s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, s.GetReferencePrefix())
+		if g.doc.Components != nil {
+			if schema, ok := g.doc.Components.Schemas[name]; ok {
+				return g.namedType(name, &schema)
+			}
+		}
+		if resolved := s.ResolveReference(); resolved != s {
+			return g.namedType(name, resolved)
+		}
+		return "any"
+	}
+	if name := s.GetName(); name != nil {
+		return g.namedType(*name, s)
+	}
+	if isStruct(s) {
+		if data, err := json.Marshal(s); err == nil {
+			if name, ok := g.shapes[string(data)]; ok {
+				schema := g.doc.Components.Schemas[name]
+				return g.namedType(name, &schema)
+			}
+		}
+	}
+	return g.typeOf(s, hint)
+}
+
+// Returns whether the schema or the component it refers to is declared as a go struct.
+func (g *generator) isStruct(s *api.Schema) bool {
+	if s.Reference != nil && s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, s.GetReferencePrefix())
+		if g.doc.Components != nil {
+			if schema, ok := g.doc.Components.Schemas[name]; ok {
+				return isStruct(&schema)
+			}
+		}
+		s = s.ResolveReference()
+	}
+	return isStruct(s)
+}
+
+// Returns the go type for a component schema, declaring it if it's an object.
+func (g *generator) namedType(name string, s *api.Schema) string {
+	if goName, ok := g.named[name]; ok {
+		return goName
+	}
+	if !isStruct(s) {
+		return g.typeOf(s, exportName(name))
+	}
+	if data, err := json.Marshal(s); err == nil && g.shareShapes {
+		if shared, ok := g.shapes[string(data)]; ok && shared != name {
+			goName := g.namedType(shared, s)
+			g.named[name] = goName
+			return goName
+		}
+	}
+	goName := g.unique(exportName(name))
+	g.named[name] = goName
+	g.declareStruct(goName, s)
+	return goName
+}
+
+// Returns the go type for the schema ignoring its name.
+func (g *generator) typeOf(s *api.Schema, hint string) string {
+	if inner := nullableOf(s); inner != nil {
+		return optionalType(g.goType(inner, hint))
+	}
+	if len(s.AllOf) == 1 {
+		return g.goType(&s.AllOf[0], hint)
+	}
+
+	switch s.Type {
+	case api.DataTypeString:
+		switch s.Format {
+		case "date-time":
+			g.imports["time"] = true
+			return "time.Time"
+		case "binary", "byte":
+			return "[]byte"
+		}
+		return "string"
+	case api.DataTypeInteger:
+		switch s.Format {
+		case "int32", "int64":
+			return s.Format
+		}
+		return "int"
+	case api.DataTypeNumber:
+		if s.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case api.DataTypeBoolean:
+		return "bool"
+	case api.DataTypeFile:
+		return "[]byte"
+	case api.DataTypeArray:
+		return "[]" + g.goType(s.Items, hint+"Item")
+	}
+	if isStruct(s) {
+		name := g.unique(hint)
+		g.declareStruct(name, s)
+		return name
+	}
+	if s.Type == api.DataTypeObject {
+		if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+			return "map[string]" + g.goType(s.AdditionalProperties.Schema, hint+"Value")
+		}
+		return "map[string]any"
+	}
+	return "any"
+}
+
+// Declares a struct type with the properties of the schema.
+func (g *generator) declareStruct(name string, s *api.Schema) {
+	properties := make([]string, 0, len(s.Properties))
+	for property := range s.Properties {
+		properties = append(properties, property)
+	}
+	sort.Strings(properties)
+
+	required := map[string]bool{}
+	for _, property := range s.Required {
+		required[property] = true
+	}
+
+	taken := map[string]bool{}
+	decl := &bytes.Buffer{}
+	description := s.Description
+	if description == "" {
+		description = s.Title
+	}
+	if description != "" {
+		writeComment(decl, "", description)
+	}
+	fmt.Fprintf(decl, "type %s struct {\n", name)
+	for _, property := range properties {
+		schema := s.Properties[property]
+		field := uniqueField(taken, exportName(property))
+		typ := g.goType(&schema, name+field)
+		tag := property
+		if !required[property] {
+			tag += ",omitempty"
+		}
+		if !required[property] || g.isStruct(&schema) {
+			// a struct can refer to itself, so struct fields are pointers
+			typ = optionalType(typ)
+		}
+		if schema.Description != "" {
+			writeComment(decl, "\t", schema.Description)
+		}
+		fmt.Fprintf(decl, "\t%s %s `json:%q`\n", field, typ, tag)
+	}
+	decl.WriteString("}\n\n")
+
+	g.types.Write(decl.Bytes())
+}
+
+// Returns the schema made nullable by the given schema, if any.
+func nullableOf(s *api.Schema) *api.Schema {
+	if s.Nullable {
+		inner := *s
+		inner.Nullable = false
+		return &inner
+	}
+	if len(s.Types) == 2 && s.Types[1] == api.DataTypeNull {
+		inner := *s
+		inner.Type = s.Types[0]
+		inner.Types = nil
+		return &inner
+	}
+	if len(s.OneOf) == 2 && s.OneOf[1].Type == api.DataTypeNull {
+		return &s.OneOf[0]
+	}
+	if len(s.AnyOf) == 2 && s.AnyOf[1].Type == api.DataTypeNull {
+		return &s.AnyOf[0]
+	}
+	return nil
+}
+
+// Returns whether a is a better name than b for a shared type.
+func shorterName(a string, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+// Returns whether the schema is declared as a go struct.
+func isStruct(s *api.Schema) bool {
+	return len(s.Properties) > 0 && nullableOf(s) == nil
+}
+
+// Returns the type which can represent a missing value of the given type.
+func optionalType(typ string) string {
+	if typ == "any" || strings.HasPrefix(typ, "*") || strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[") || typ == "io.Reader" {
+		return typ
+	}
+	return "*" + typ
+}
+
+// Returns the JSON content, if any.
+func jsonContent(content api.Contents) (api.ContentType, *api.MediaType) {
+	for _, contentType := range sortedContentTypes(content) {
+		if strings.Contains(string(contentType), "json") && content[contentType] != nil {
+			return contentType, content[contentType]
+		}
+	}
+	return api.ContentTypeNone, nil
+}
+
+// Returns whether there is text content.
+func textContent(content api.Contents) bool {
+	for contentType := range content {
+		if strings.HasPrefix(string(contentType), "text/") {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedContentTypes(content api.Contents) []api.ContentType {
+	contentTypes := make([]api.ContentType, 0, len(content))
+	for contentType := range content {
+		contentTypes = append(contentTypes, contentType)
+	}
+	sort.Slice(contentTypes, func(i, j int) bool {
+		return contentTypes[i] < contentTypes[j]
+	})
+	return contentTypes
+}
+
+// Returns the go expression which builds the path of the operation.
+func pathExpression(path string, fields []paramField) string {
+	parts := make([]string, 0)
+	for len(path) > 0 {
+		start := strings.Index(path, "{")
+		end := strings.Index(path, "}")
+		if start == -1 || end < start {
+			parts = append(parts, strconv.Quote(path))
+			break
+		}
+		if start > 0 {
+			parts = append(parts, strconv.Quote(path[:start]))
+		}
+		name := strings.SplitN(path[start+1:end], ":", 2)[0]
+		found := false
+		for _, field := range fields {
+			if field.param.In == api.ParameterInPath && field.param.Name == name {
+				style, explode := api.GetStyle(field.param.In, field.param.Style, field.param.Explode)
+				parts = append(parts, fmt.Sprintf("pathValue(%q, %q, %t, params.%s)", name, style, explode, field.name))
+				found = true
+				break
+			}
+		}
+		if !found {
+			parts = append(parts, strconv.Quote(path[start:end+1]))
+		}
+		path = path[end+1:]
+	}
+	if len(parts) == 0 {
+		return `""`
+	}
+	return strings.Join(parts, " + ")
+}
+
+// Returns a method name for an operation without an OperationID, like GetTaskByID.
+func operationName(method string, path string) string {
+	name := exportName(method)
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" {
+			continue
+		}
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			name += "By" + exportName(strings.SplitN(segment[1:len(segment)-1], ":", 2)[0])
+		} else {
+			name += exportName(segment)
+		}
+	}
+	return name
+}
+
+// The names of the rez results for their status.
+var resultNames = map[int]string{
+	http.StatusMovedPermanently: "Moved",
+}
+
+// Returns the field name for the status, like NotFound for 404.
+func statusName(status string) string {
+	if status == "default" {
+		return "Default"
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return "Status" + strings.ToUpper(status)
+	}
+	if name, ok := resultNames[code]; ok {
+		return name
+	}
+	if text := http.StatusText(code); text != "" {
+		return exportName(text)
+	}
+	return "Status" + status
+}
+
+func statusText(status string) string {
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return ""
+	}
+	return http.StatusText(code)
+}
+
+// Returns the condition for the status, like res.StatusCode/100 == 4 for 4XX.
+func statusCondition(status string) string {
+	if _, err := strconv.Atoi(status); err == nil {
+		return "res.StatusCode == " + status
+	}
+	if len(status) == 3 && strings.ToUpper(status[1:]) == "XX" {
+		return fmt.Sprintf("res.StatusCode/100 == %c", status[0])
+	}
+	return "false"
+}
+
+// Words which are all upper case in go names.
+var initialisms = map[string]bool{
+	"api": true, "html": true, "http": true, "https": true, "id": true, "ip": true,
+	"json": true, "ok": true, "sql": true, "uri": true, "url": true, "uuid": true, "xml": true,
+}
+
+// Converts the name to an exported go identifier, like XToken for X-Token and UserID for user_id.
+func exportName(name string) string {
+	words := make([]string, 0)
+	word := []rune{}
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = word[:0]
+			}
+			continue
+		}
+		if unicode.IsUpper(r) && len(word) > 0 && (unicode.IsLower(word[len(word)-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+
+	out := strings.Builder{}
+	for _, w := range words {
+		lower := strings.ToLower(w)
+		if initialisms[lower] {
+			out.WriteString(strings.ToUpper(lower))
+		} else {
+			first := []rune(w)
+			first[0] = unicode.ToUpper(first[0])
+			out.WriteString(string(first))
+		}
+	}
+
+	exported := out.String()
+	if exported == "" {
+		return "Value"
+	}
+	if unicode.IsDigit([]rune(exported)[0]) {
+		return "N" + exported
+	}
+	return exported
+}
+
+func uniqueField(taken map[string]bool, name string) string {
+	candidate := name
+	for i := 2; taken[candidate]; i++ {
+		candidate = name + strconv.Itoa(i)
+	}
+	taken[candidate] = true
+	return candidate
+}
+
+// Writes the text as a go comment with the given indentation.
+func writeComment(out *bytes.Buffer, indent string, text string) {
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			out.WriteString(indent + "//\n")
+		} else {
+			out.WriteString(indent + "// " + line + "\n")
+		}
+	}
+}
+
+// The packages the client runtime uses.
+var clientImports = []string{"bytes", "context", "encoding/json", "fmt", "io", "net/http", "net/url", "sort", "strings"}
+
+// The code every generated client has. $Client is replaced with the client name.
+const clientRuntime = `// A client for the API. The zero value is not usable, use New$Client.
+type $Client struct {
+	// The URL the operation paths are relative to, like https://api.example.com.
+	BaseURL string
+	// The client which sends the requests, defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// Headers sent with every request, like Authorization.
+	Header http.Header
+}
+
+// Creates a client for the API at the given URL.
+func New$Client(baseURL string) *$Client {
+	return &$Client{
+		BaseURL:    baseURL,
+		HTTPClient: http.DefaultClient,
+		Header:     http.Header{},
+	}
+}
+
+// The error returned when the response has a status the operation doesn't document.
+type StatusError struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.Status, strings.TrimSpace(string(e.Body)))
+}
+
+// A request being built for an operation.
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	header      http.Header
+	cookies     []*http.Cookie
+	body        io.Reader
+	contentType string
+}
+
+// Adds the value to the query with the style of the parameter. Exploded arrays repeat
+// the parameter, exploded form objects are a parameter for each property, and deep
+// objects have each property in brackets after the name.
+func (r *request) setQuery(name string, style string, explode bool, value any) {
+	parsed, ok := parse(value)
+	if !ok || parsed == nil {
+		return
+	}
+	if r.query == nil {
+		r.query = url.Values{}
+	}
+	switch v := parsed.(type) {
+	case []any:
+		if explode {
+			for _, item := range v {
+				r.query.Add(name, textOf(item))
+			}
+			return
+		}
+	case map[string]any:
+		if style == "deepObject" {
+			flatten(r.query, name, v)
+			return
+		}
+		if style == "form" && explode {
+			for _, key := range sortedKeys(v) {
+				r.query.Add(key, textOf(v[key]))
+			}
+			return
+		}
+	}
+	text, _ := styleValue(name, style, explode, parsed, nil)
+	r.query.Add(name, text)
+}
+
+// Sets the header to the value with the simple style.
+func (r *request) setHeader(name string, explode bool, value any) {
+	text, ok := styleValue(name, "simple", explode, value, nil)
+	if !ok {
+		return
+	}
+	if r.header == nil {
+		r.header = http.Header{}
+	}
+	r.header.Set(name, text)
+}
+
+// Adds the value as cookies with the form style. Exploded arrays are a cookie
+// for each item and exploded objects are a cookie for each property.
+func (r *request) setCookie(name string, explode bool, value any) {
+	parsed, ok := parse(value)
+	if !ok || parsed == nil {
+		return
+	}
+	if explode {
+		switch v := parsed.(type) {
+		case []any:
+			for _, item := range v {
+				r.cookies = append(r.cookies, &http.Cookie{Name: name, Value: textOf(item)})
+			}
+			return
+		case map[string]any:
+			for _, key := range sortedKeys(v) {
+				r.cookies = append(r.cookies, &http.Cookie{Name: key, Value: textOf(v[key])})
+			}
+			return
+		}
+	}
+	text, _ := styleValue(name, "form", false, parsed, nil)
+	r.cookies = append(r.cookies, &http.Cookie{Name: name, Value: text})
+}
+
+func (r *request) setJSON(contentType string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	r.body = bytes.NewReader(data)
+	r.contentType = contentType
+	return nil
+}
+
+// Sends the request and reads the response body.
+func (c *$Client) send(ctx context.Context, r request) (*http.Response, []byte, error) {
+	target := strings.TrimRight(c.BaseURL, "/") + r.path
+	if len(r.query) > 0 {
+		target += "?" + r.query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, target, r.body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, values := range c.Header {
+		req.Header[name] = values
+	}
+	for name, values := range r.header {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	for _, cookie := range r.cookies {
+		req.AddCookie(cookie)
+	}
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json, */*;q=0.5")
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, data, nil
+}
+
+// Decodes the response body into the target.
+func decode(res *http.Response, data []byte, target any) error {
+	switch t := target.(type) {
+	case **string:
+		if !strings.Contains(res.Header.Get("Content-Type"), "json") {
+			text := string(data)
+			*t = &text
+			return nil
+		}
+	case *[]byte:
+		*t = data
+		return nil
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, target)
+}
+
+// Returns the value as it appears in a path with the given style.
+func pathValue(name string, style string, explode bool, value any) string {
+	text, _ := styleValue(name, style, explode, value, url.PathEscape)
+	return text
+}
+
+// Returns the value serialized with the style of a path, query, header, or cookie parameter,
+// like a,b for an array with the simple style. The items, property names, and values are
+// escaped with the given function, if any. False is returned if there is no value.
+func styleValue(name string, style string, explode bool, value any, escape func(string) string) (string, bool) {
+	parsed, ok := parse(value)
+	if !ok || parsed == nil {
+		return "", false
+	}
+	if escape == nil {
+		escape = func(s string) string { return s }
+	}
+
+	prefix, separator := "", ","
+	switch style {
+	case "label":
+		prefix = "."
+		if explode {
+			separator = "."
+		}
+	case "matrix":
+		prefix = ";" + name + "="
+		if explode {
+			separator = ";" + name + "="
+		}
+	case "spaceDelimited":
+		separator = " "
+	case "pipeDelimited":
+		separator = "|"
+	}
+
+	switch v := parsed.(type) {
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = escape(textOf(item))
+		}
+		return prefix + strings.Join(items, separator), true
+	case map[string]any:
+		parts := make([]string, 0, len(v)*2)
+		for _, key := range sortedKeys(v) {
+			if explode {
+				parts = append(parts, escape(key)+"="+escape(textOf(v[key])))
+			} else {
+				parts = append(parts, escape(key), escape(textOf(v[key])))
+			}
+		}
+		if style == "matrix" && explode {
+			prefix, separator = ";", ";"
+		}
+		return prefix + strings.Join(parts, separator), true
+	}
+	return prefix + escape(textOf(parsed)), true
+}
+
+// Returns the value as JSON decoded into nil, []any, map[string]any, json.Number, string, or bool.
+func parse(value any) (any, bool) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var parsed any
+	if decoder.Decode(&parsed) != nil {
+		return nil, false
+	}
+	return parsed, true
+}
+
+// Returns the text of a parsed value, arrays and objects are JSON.
+func textOf(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return fmt.Sprint(v)
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+func sortedKeys(object map[string]any) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Adds the parsed value to the values the way rez parses deep objects, arrays and
+// objects have their index or property in brackets after the name.
+func flatten(values url.Values, name string, value any) {
+	switch v := value.(type) {
+	case nil:
+	case []any:
+		for i, item := range v {
+			flatten(values, fmt.Sprintf("%s[%d]", name, i), item)
+		}
+	case map[string]any:
+		for _, key := range sortedKeys(v) {
+			flatten(values, name+"["+key+"]", v[key])
+		}
+	default:
+		values.Add(name, textOf(v))
+	}
+}
+
+`
@@ -0,0 +1,369 @@
+package gen
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ClickerMonkey/rez"
+	"github.com/ClickerMonkey/rez/api"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type taskPath struct {
+	ID int `json:"id"`
+}
+type taskQuery struct {
+	Limit *int     `json:"limit,omitempty"`
+	Tags  []string `json:"tags"`
+}
+type taskHeader struct {
+	Token string `json:"X-Token"`
+}
+type task struct {
+	ID     int               `json:"id"`
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+func TestClient(t *testing.T) {
+	site := rez.New(chi.NewRouter())
+	site.Open.Document.Info.Title = "Tasks"
+	site.Get("/task/{id}", func(p rez.Path[taskPath], q rez.Query[taskQuery], h rez.Header[taskHeader]) (*task, *rez.NotFound[string], *rez.BadRequest[rez.Validation]) {
+		return nil, nil, nil
+	})
+	site.Post("/task", func(b rez.Body[task]) *rez.Created[task] {
+		return nil
+	}, api.Operation{OperationID: "createTask", Summary: "Creates a task"})
+	site.Delete("/task/{id}", func(p rez.Path[taskPath]) *rez.NotFound[string] {
+		return nil
+	})
+
+	source, err := Client(site.BuildDocument(), ClientOptions{Package: "tasks"})
+	if !assert.NoError(t, err, string(source)) {
+		return
+	}
+
+	doc := api.Document{}
+	if assert.NoError(t, json.Unmarshal(site.BuildJSON(), &doc)) {
+		fromJSON, err := Client(&doc, ClientOptions{Package: "tasks"})
+		assert.NoError(t, err)
+		assert.Equal(t, string(source), string(fromJSON))
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "client.go", source, parser.ParseComments)
+	if !assert.NoError(t, err) {
+		return
+	}
+	config := types.Config{Importer: importer.Default()}
+	pkg, err := config.Check("tasks", fset, []*ast.File{file}, nil)
+	if !assert.NoError(t, err, string(source)) {
+		return
+	}
+
+	scope := pkg.Scope()
+	assert.NotNil(t, scope.Lookup("NewClient"))
+	assert.NotNil(t, scope.Lookup("Task"))
+
+	client := types.NewPointer(scope.Lookup("Client").Type())
+	methods := map[string]string{
+		"GetTaskByID":    "func(ctx context.Context, params tasks.GetTaskByIDParams) (*tasks.GetTaskByIDResult, error)",
+		"CreateTask":     "func(ctx context.Context, params tasks.CreateTaskParams) (*tasks.CreateTaskResult, error)",
+		"DeleteTaskByID": "func(ctx context.Context, params tasks.DeleteTaskByIDParams) (*tasks.DeleteTaskByIDResult, error)",
+	}
+	for name, signature := range methods {
+		method, _, _ := types.LookupFieldOrMethod(client, true, pkg, name)
+		if assert.NotNil(t, method, name) {
+			assert.Equal(t, signature, method.Type().String())
+		}
+	}
+
+	fields := func(name string) map[string]string {
+		out := map[string]string{}
+		s := scope.Lookup(name).Type().Underlying().(*types.Struct)
+		for i := 0; i < s.NumFields(); i++ {
+			out[s.Field(i).Name()] = s.Field(i).Type().String()
+		}
+		return out
+	}
+
+	assert.Equal(t, map[string]string{
+		"ID":     "int",
+		"Limit":  "*int",
+		"Tags":   "[]string",
+		"XToken": "string",
+	}, fields("GetTaskByIDParams"))
+	assert.Equal(t, map[string]string{
+		"Status":              "int",
+		"Header":              "net/http.Header",
+		"OK":                  "*tasks.Task",
+		"BadRequest":          "*tasks.BadRequestValidation",
+		"NotFound":            "*string",
+		"InternalServerError": "*tasks.InternalError",
+	}, fields("GetTaskByIDResult"))
+	assert.Equal(t, map[string]string{
+		"Body": "tasks.Task",
+	}, fields("CreateTaskParams"))
+	assert.Equal(t, map[string]string{
+		"Status":              "int",
+		"Header":              "net/http.Header",
+		"Created":             "*tasks.Task",
+		"InternalServerError": "*tasks.InternalError",
+	}, fields("CreateTaskResult"))
+	assert.Equal(t, map[string]string{
+		"ID":     "int",
+		"Labels": "map[string]string",
+		"Name":   "string",
+	}, fields("Task"))
+}
+
+const nodeSpec = `
+openapi: 3.0.3
+info:
+  title: Nodes
+  version: 1.0.0
+paths:
+  /node:
+    get:
+      operationId: getNode
+      responses:
+        "200":
+          description: The node.
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Node'
+components:
+  schemas:
+    Node:
+      type: object
+      required: [name, parent]
+      properties:
+        name:
+          type: string
+        parent:
+          $ref: '#/components/schemas/Node'
+`
+
+func TestClientRecursive(t *testing.T) {
+	doc, err := api.ParseDocument([]byte(nodeSpec))
+	if !assert.NoError(t, err) {
+		return
+	}
+	source, err := Client(doc, ClientOptions{Package: "nodes"})
+	if !assert.NoError(t, err, string(source)) {
+		return
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "client.go", source, parser.ParseComments)
+	if !assert.NoError(t, err) {
+		return
+	}
+	config := types.Config{Importer: importer.Default()}
+	pkg, err := config.Check("nodes", fset, []*ast.File{file}, nil)
+	if !assert.NoError(t, err, string(source)) {
+		return
+	}
+	node := pkg.Scope().Lookup("Node").Type().Underlying().(*types.Struct)
+	assert.Equal(t, "*nodes.Node", node.Field(1).Type().String())
+}
+
+type echoPoint struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+type echoPath struct {
+	IDs   []int     `json:"ids"`
+	Point echoPoint `json:"point" api:"style=matrix,explode=true"`
+}
+type echoWindow struct {
+	Offset int `json:"offset"`
+	Size   int `json:"size"`
+}
+type echoQuery struct {
+	Names  []string   `json:"names"`
+	Limit  *int       `json:"limit,omitempty"`
+	Flat   []string   `json:"flat" api:"explode=false"`
+	Spaced []string   `json:"spaced" api:"style=spaceDelimited"`
+	Piped  []int      `json:"piped" api:"style=pipeDelimited"`
+	Corner echoPoint  `json:"corner" api:"explode=false"`
+	Deep   echoPoint  `json:"deep" api:"style=deepObject"`
+	Window echoWindow `json:"window"`
+}
+type echoHeader struct {
+	Tags  []string  `json:"X-Tags"`
+	Point echoPoint `json:"X-Point" api:"explode=true"`
+}
+type echoCookie struct {
+	Session string     `json:"session"`
+	Crumbs  []string   `json:"crumbs"`
+	Flavors []string   `json:"flavors" api:"explode=false"`
+	Spot    echoPoint  `json:"spot" api:"explode=false"`
+	Bite    echoWindow `json:"bite"`
+}
+type echoed struct {
+	Path   echoPath   `json:"path"`
+	Query  echoQuery  `json:"query"`
+	Header echoHeader `json:"header"`
+	Cookie echoCookie `json:"cookie"`
+}
+
+const echoProgram = `package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"echo/client"
+)
+
+func main() {
+	limit := 5
+	c := client.NewClient(os.Args[1])
+	res, err := c.Echo(context.Background(), client.EchoParams{
+		Ids:     []int{3, 1, 2, 10, 4, 9, 8, 7, 6, 5},
+		Point:   client.EchoPoint{X: 1, Y: 2},
+		Names:   []string{"c", "a", "b"},
+		Limit:   &limit,
+		Flat:    []string{"d", "e"},
+		Spaced:  []string{"f", "h"},
+		Piped:   []int{7, 8},
+		Corner:  client.EchoPoint{X: 5, Y: 6},
+		Deep:    client.EchoPoint{X: 7, Y: 8},
+		Window:  client.EchoWindow{Offset: 20, Size: 10},
+		XTags:   []string{"z", "y", "x", "w", "v", "u", "t", "s", "r", "q"},
+		XPoint:  client.EchoPoint{X: 3, Y: 4},
+		Session: "abc",
+		Crumbs:  []string{"i", "j"},
+		Flavors: []string{"k", "l"},
+		Spot:    client.EchoPoint{X: 9, Y: 10},
+		Bite:    client.EchoWindow{Offset: 30, Size: 15},
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	out, _ := json.Marshal(res.OK)
+	fmt.Println(res.Status, string(out))
+}
+`
+
+func TestClientRequests(t *testing.T) {
+	goCommand, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("the go command is required to run a generated client")
+	}
+
+	site := rez.New(chi.NewRouter())
+	site.Get("/echo/{ids}/{point}", func(p rez.Path[echoPath], q rez.Query[echoQuery], h rez.Header[echoHeader], c rez.Cookie[echoCookie]) *echoed {
+		return &echoed{Path: p.Value, Query: q.Value, Header: h.Value, Cookie: c.Value}
+	}, api.Operation{OperationID: "echo"})
+
+	source, err := Client(site.BuildDocument(), ClientOptions{Package: "client"})
+	if !assert.NoError(t, err, string(source)) {
+		return
+	}
+
+	// rez also parses properties and indices in brackets, so the query is checked as sent.
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		site.Chi().ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":           "module echo\n\ngo 1.19\n",
+		"main.go":          echoProgram,
+		"client/client.go": string(source),
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	cmd := exec.Command(goCommand, "run", ".", server.URL)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off")
+	out, err := cmd.CombinedOutput()
+	if !assert.NoError(t, err, string(out)) {
+		return
+	}
+
+	expected := echoed{
+		Path: echoPath{IDs: []int{3, 1, 2, 10, 4, 9, 8, 7, 6, 5}, Point: echoPoint{X: 1, Y: 2}},
+		Query: echoQuery{
+			Names:  []string{"c", "a", "b"},
+			Flat:   []string{"d", "e"},
+			Spaced: []string{"f", "h"},
+			Piped:  []int{7, 8},
+			Corner: echoPoint{X: 5, Y: 6},
+			Deep:   echoPoint{X: 7, Y: 8},
+			Window: echoWindow{Offset: 20, Size: 10},
+		},
+		Header: echoHeader{Tags: []string{"z", "y", "x", "w", "v", "u", "t", "s", "r", "q"}, Point: echoPoint{X: 3, Y: 4}},
+		Cookie: echoCookie{
+			Session: "abc",
+			Crumbs:  []string{"i", "j"},
+			Flavors: []string{"k", "l"},
+			Spot:    echoPoint{X: 9, Y: 10},
+			Bite:    echoWindow{Offset: 30, Size: 15},
+		},
+	}
+	limit := 5
+	expected.Query.Limit = &limit
+
+	status, body, _ := strings.Cut(strings.TrimSpace(string(out)), " ")
+	assert.Equal(t, "200", status)
+	assert.Equal(t, url.Values{
+		"names":   {"c", "a", "b"},
+		"limit":   {"5"},
+		"flat":    {"d,e"},
+		"spaced":  {"f h"},
+		"piped":   {"7|8"},
+		"corner":  {"x,5,y,6"},
+		"deep[x]": {"7"},
+		"deep[y]": {"8"},
+		"offset":  {"20"},
+		"size":    {"10"},
+	}, query)
+	actual := echoed{}
+	if assert.NoError(t, json.Unmarshal([]byte(body), &actual), body) {
+		assert.Equal(t, expected, actual)
+	}
+}
+
+func TestExportName(t *testing.T) {
+	tests := map[string]string{
+		"id":         "ID",
+		"X-Token":    "XToken",
+		"user_id":    "UserID",
+		"doneAt":     "DoneAt",
+		"createTask": "CreateTask",
+		"HTTPServer": "HTTPServer",
+		"Not Found":  "NotFound",
+		"2fa":        "N2fa",
+		"":           "Value",
+	}
+	for name, expected := range tests {
+		assert.Equal(t, expected, exportName(name), name)
+	}
+}
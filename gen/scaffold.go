@@ -0,0 +1,267 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ClickerMonkey/rez/api"
+)
+
+// Options for scaffolding handlers.
+type ScaffoldOptions struct {
+	// The package name of the generated code, defaults to "handlers".
+	Package string
+}
+
+// Generates Go types for the component schemas in the document and a stub rez handler
+// for each operation, along with a Register function which adds the handlers to a
+// rez.Router. Handlers take their parameters with rez.Path, rez.Query, rez.Header, and
+// rez.Cookie and the request body with rez.Body, and return the rez result type for
+// each documented status. The generated code is a starting point meant to be edited.
+//
+//	doc, err := api.ParseDocument(spec)
+//	source, err := gen.Scaffold(doc, gen.ScaffoldOptions{Package: "tasks"})
+func Scaffold(doc *api.Document, options ScaffoldOptions) ([]byte, error) {
+	if options.Package == "" {
+		options.Package = "handlers"
+	}
+
+	g := newGenerator(doc)
+	g.imports["github.com/ClickerMonkey/rez"] = true
+	g.reserve("Register")
+
+	if doc.Components != nil {
+		names := make([]string, 0, len(doc.Components.Schemas))
+		for name := range doc.Components.Schemas {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			schema := doc.Components.Schemas[name]
+			g.namedType(name, &schema)
+		}
+	}
+
+	handlers := &bytes.Buffer{}
+	register := &bytes.Buffer{}
+	for _, op := range g.operations() {
+		g.writeHandler(handlers, register, op)
+	}
+
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "// Scaffolded from %s by github.com/ClickerMonkey/rez/gen.\n\n", g.title())
+	fmt.Fprintf(out, "package %s\n\n", options.Package)
+	out.WriteString("import (\n")
+	for _, imp := range g.importList() {
+		fmt.Fprintf(out, "\t%q\n", imp)
+	}
+	out.WriteString(")\n\n")
+	out.WriteString("// Adds the operations to the router.\n")
+	out.WriteString("func Register(r rez.Router) {\n")
+	out.Write(register.Bytes())
+	out.WriteString("}\n\n")
+	out.Write(g.types.Bytes())
+	out.Write(handlers.Bytes())
+
+	source, err := format.Source(out.Bytes())
+	if err != nil {
+		return out.Bytes(), fmt.Errorf("scaffolded code is invalid: %w", err)
+	}
+	return source, nil
+}
+
+// The rez injectable for each parameter location and the name of its argument.
+var scaffoldParams = []struct {
+	in       api.ParameterIn
+	arg      string
+	wrapper  string
+	typeName string
+}{
+	{api.ParameterInPath, "path", "Path", "Path"},
+	{api.ParameterInQuery, "query", "Query", "Query"},
+	{api.ParameterInHeader, "header", "Header", "Header"},
+	{api.ParameterInCookie, "cookie", "Cookie", "Cookie"},
+}
+
+// The rez result types by the status they send.
+var scaffoldResults = map[int]string{
+	http.StatusCreated:             "Created",
+	http.StatusAccepted:            "Accepted",
+	http.StatusMovedPermanently:    "Moved",
+	http.StatusBadRequest:          "BadRequest",
+	http.StatusUnauthorized:        "Unauthorized",
+	http.StatusPaymentRequired:     "PaymentRequired",
+	http.StatusForbidden:           "Forbidden",
+	http.StatusNotFound:            "NotFound",
+	http.StatusConflict:            "Conflict",
+	http.StatusTooManyRequests:     "TooManyRequests",
+	http.StatusInternalServerError: "InternalServerError",
+	http.StatusNotImplemented:      "NotImplemented",
+	http.StatusServiceUnavailable:  "ServiceUnavailable",
+}
+
+// Writes the parameter types and handler for the operation and adds it to Register.
+func (g *generator) writeHandler(out *bytes.Buffer, register *bytes.Buffer, op operation) {
+	args := make([]string, 0)
+
+	for _, location := range scaffoldParams {
+		params := make([]api.Parameter, 0)
+		for _, param := range op.params {
+			if param.In == location.in {
+				params = append(params, param)
+			}
+		}
+		if len(params) == 0 {
+			continue
+		}
+		typeName := g.unique(op.name + location.typeName)
+		g.declareParams(typeName, location.in, params)
+		args = append(args, fmt.Sprintf("%s rez.%s[%s]", location.arg, location.wrapper, typeName))
+	}
+
+	if body := g.resolveRequestBody(op.op.RequestBody); body != nil && len(body.Content) > 0 {
+		_, media := jsonContent(body.Content)
+		if media == nil {
+			media = body.Content[sortedContentTypes(body.Content)[0]]
+		}
+		if media != nil && media.Schema != nil {
+			args = append(args, fmt.Sprintf("body rez.Body[%s]", g.goType(media.Schema, op.name+"Body")))
+		}
+	}
+
+	returns, unsupported := g.handlerResults(op)
+
+	comment := op.op.Summary
+	if op.op.Description != "" {
+		comment = strings.TrimSpace(comment + "\n\n" + op.op.Description)
+	}
+	if comment != "" {
+		comment += "\n\n"
+	}
+	comment += op.method + " " + op.path
+	if len(unsupported) > 0 {
+		comment += "\n\nThere is no rez result for the documented " + strings.Join(unsupported, ", ") +
+			" status, return a type which implements rez.HasStatus to send it."
+	}
+	if op.op.Deprecated {
+		comment += "\n\nDeprecated: this operation is deprecated."
+	}
+	writeComment(out, "", comment)
+	fmt.Fprintf(out, "func %s(%s)", op.name, strings.Join(args, ", "))
+	switch len(returns) {
+	case 0:
+	case 1:
+		fmt.Fprintf(out, " %s", returns[0])
+	default:
+		fmt.Fprintf(out, " (%s)", strings.Join(returns, ", "))
+	}
+	fmt.Fprintf(out, " {\n\tpanic(%q)\n}\n\n", "TODO implement "+op.name)
+
+	operation := g.operationLiteral(op.op)
+	if operation != "" {
+		operation = ", " + operation
+	}
+	fmt.Fprintf(register, "\tr.%s(%q, %s%s)\n", exportName(strings.ToLower(op.method)), op.path, op.name, operation)
+}
+
+// Declares a struct for the parameters in a location.
+func (g *generator) declareParams(name string, in api.ParameterIn, params []api.Parameter) {
+	taken := map[string]bool{}
+	decl := &bytes.Buffer{}
+	writeComment(decl, "", fmt.Sprintf("The %s parameters of %s.", in, strings.TrimSuffix(name, exportName(string(in)))))
+	fmt.Fprintf(decl, "type %s struct {\n", name)
+	for _, param := range params {
+		field := uniqueField(taken, exportName(param.Name))
+		typ := "string"
+		if param.Schema != nil {
+			typ = g.goType(param.Schema, name+field)
+		}
+		tag := param.Name
+		if !param.Required {
+			typ = optionalType(typ)
+			tag += ",omitempty"
+		}
+		if param.Description != "" {
+			writeComment(decl, "\t", param.Description)
+		}
+		fmt.Fprintf(decl, "\t%s %s `json:%q`\n", field, typ, tag)
+	}
+	decl.WriteString("}\n\n")
+
+	g.types.Write(decl.Bytes())
+}
+
+// Returns the return types of the handler and the statuses which don't have a rez result.
+func (g *generator) handlerResults(op operation) (returns []string, unsupported []string) {
+	statuses := make([]string, 0, len(op.op.Responses))
+	for status := range op.op.Responses {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+
+	for _, status := range statuses {
+		code, err := strconv.Atoi(status)
+		result, hasResult := scaffoldResults[code]
+		if err != nil || (code != http.StatusOK && !hasResult) {
+			unsupported = append(unsupported, status)
+			continue
+		}
+
+		typ := ""
+		if response := g.resolveResponse(op.op.Responses[status]); response != nil && len(response.Content) > 0 {
+			if _, media := jsonContent(response.Content); media != nil {
+				typ = g.goType(media.Schema, op.name+statusName(status))
+			} else if textContent(response.Content) {
+				typ = "string"
+			} else {
+				typ = "[]byte"
+			}
+		}
+
+		if code == http.StatusOK {
+			if typ != "" {
+				returns = append(returns, optionalType(typ))
+			}
+		} else {
+			if typ == "" {
+				typ = "string"
+			}
+			returns = append(returns, "*rez."+result+"["+typ+"]")
+		}
+	}
+	return
+}
+
+// Returns the api.Operation literal which documents what the handler can't, if anything.
+func (g *generator) operationLiteral(op *api.Operation) string {
+	fields := make([]string, 0)
+	if op.OperationID != "" {
+		fields = append(fields, "OperationID: "+strconv.Quote(op.OperationID))
+	}
+	if op.Summary != "" {
+		fields = append(fields, "Summary: "+strconv.Quote(op.Summary))
+	}
+	if op.Description != "" {
+		fields = append(fields, "Description: "+strconv.Quote(op.Description))
+	}
+	if len(op.Tags) > 0 {
+		tags := make([]string, len(op.Tags))
+		for i, tag := range op.Tags {
+			tags[i] = strconv.Quote(tag)
+		}
+		fields = append(fields, "Tags: []string{"+strings.Join(tags, ", ")+"}")
+	}
+	if op.Deprecated {
+		fields = append(fields, "Deprecated: true")
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	g.imports["github.com/ClickerMonkey/rez/api"] = true
+	return "api.Operation{" + strings.Join(fields, ", ") + "}"
+}
@@ -0,0 +1,171 @@
+package gen
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/ClickerMonkey/rez/api"
+	"github.com/stretchr/testify/assert"
+)
+
+const petsSpec = `
+openapi: 3.0.3
+info:
+  title: Pets
+  version: 1.0.0
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      tags: [pets]
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+            format: int32
+      responses:
+        "200":
+          description: The pets.
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/Pet'
+    post:
+      summary: Adds a pet.
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/NewPet'
+      responses:
+        "201":
+          description: The added pet.
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+        "400":
+          description: The pet is invalid.
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+        schema:
+          type: integer
+          format: int64
+    get:
+      parameters:
+        - name: X-Request-ID
+          in: header
+          schema:
+            type: string
+      responses:
+        "200":
+          description: The pet.
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+        "404":
+          description: The pet was not found.
+          content:
+            text/plain:
+              schema:
+                type: string
+    delete:
+      responses:
+        "204":
+          description: The pet was deleted.
+components:
+  schemas:
+    NewPet:
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string
+        born:
+          type: string
+          format: date-time
+    Pet:
+      type: object
+      required: [id, name]
+      properties:
+        id:
+          type: integer
+          format: int64
+        name:
+          type: string
+        owner:
+          $ref: '#/components/schemas/Owner'
+        category:
+          $ref: '#/components/schemas/Category'
+    Category:
+      type: object
+      required: [name, parent]
+      properties:
+        name:
+          type: string
+        parent:
+          $ref: '#/components/schemas/Category'
+    Owner:
+      type: object
+      properties:
+        name:
+          type: string
+`
+
+func TestScaffold(t *testing.T) {
+	doc, err := api.ParseDocument([]byte(petsSpec))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	source, err := Scaffold(doc, ScaffoldOptions{Package: "pets"})
+	if !assert.NoError(t, err, string(source)) {
+		return
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "handlers.go", source, parser.ParseComments)
+	if !assert.NoError(t, err) {
+		return
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check("pets", fset, []*ast.File{file}, nil)
+	if !assert.NoError(t, err, string(source)) {
+		return
+	}
+
+	signature := func(name string) string {
+		obj := pkg.Scope().Lookup(name)
+		if obj == nil {
+			return ""
+		}
+		return types.TypeString(obj.Type(), types.RelativeTo(pkg))
+	}
+
+	assert.Equal(t, "func(query github.com/ClickerMonkey/rez.Query[ListPetsQuery]) []Pet", signature("ListPets"))
+	assert.Equal(t, "func(body github.com/ClickerMonkey/rez.Body[NewPet]) (*github.com/ClickerMonkey/rez.Created[Pet], *github.com/ClickerMonkey/rez.BadRequest[string])", signature("PostPets"))
+	assert.Equal(t, "func(path github.com/ClickerMonkey/rez.Path[GetPetsByPetIDPath], header github.com/ClickerMonkey/rez.Header[GetPetsByPetIDHeader]) (*Pet, *github.com/ClickerMonkey/rez.NotFound[string])", signature("GetPetsByPetID"))
+	assert.Equal(t, "func(path github.com/ClickerMonkey/rez.Path[DeletePetsByPetIDPath])", signature("DeletePetsByPetID"))
+	assert.Equal(t, "func(r github.com/ClickerMonkey/rez.Router)", signature("Register"))
+	underlying := func(name string) string {
+		obj := pkg.Scope().Lookup(name)
+		if obj == nil {
+			return ""
+		}
+		return types.TypeString(obj.Type().Underlying(), types.RelativeTo(pkg))
+	}
+	assert.Equal(t, "struct{Category *Category \"json:\\\"category,omitempty\\\"\"; ID int64 \"json:\\\"id\\\"\"; Name string \"json:\\\"name\\\"\"; Owner *Owner \"json:\\\"owner,omitempty\\\"\"}", underlying("Pet"))
+	assert.Equal(t, "struct{Name string \"json:\\\"name\\\"\"; Parent *Category \"json:\\\"parent\\\"\"}", underlying("Category"))
+	assert.Equal(t, "struct{Limit *int32 \"json:\\\"limit,omitempty\\\"\"}", underlying("ListPetsQuery"))
+}
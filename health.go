@@ -0,0 +1,183 @@
+package rez
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ClickerMonkey/deps"
+	"github.com/ClickerMonkey/rez/api"
+)
+
+// How long a health check can take before it's considered down, unless the check has a timeout.
+var DefaultHealthTimeout = 5 * time.Second
+
+// A health check of a dependency of the site. Check is a dependency injectable function
+// which returns nothing or an error, the check is down if it returns an error or panics.
+// The context.Context injected into the check is done when the check times out.
+//
+//	rez.HealthCheck{Name: "database", Check: func(ctx context.Context, db *sql.DB) error {
+//		return db.PingContext(ctx)
+//	}}
+type HealthCheck struct {
+	// The name of the check in the response.
+	Name string
+	// The dependency injectable function which performs the check.
+	Check any
+	// How long the check can take, defaults to DefaultHealthTimeout.
+	Timeout time.Duration
+}
+
+// The status of a health check or of all the checks.
+type HealthStatus string
+
+const (
+	HealthStatusUp   HealthStatus = "up"
+	HealthStatusDown HealthStatus = "down"
+)
+
+func (HealthStatus) APIEnum() []any {
+	return []any{HealthStatusUp, HealthStatusDown}
+}
+
+// The result of the health checks. The status is up only if every check is up,
+// and it is sent as a 200 when up and a 503 when down.
+type Health struct {
+	// The status of all the checks.
+	Status HealthStatus `json:"status" xml:"status"`
+	// The result of each check.
+	Checks []HealthCheckResult `json:"checks" xml:"checks>check"`
+}
+
+var _ HasStatus = Health{}
+
+func (h Health) HTTPStatus() int {
+	if h.Status == HealthStatusUp {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
+func (h Health) HTTPStatuses() []int {
+	return []int{http.StatusOK, http.StatusServiceUnavailable}
+}
+func (h Health) APIDescription() string {
+	return "The health of the service, sent as a 200 when every check is up and a 503 otherwise."
+}
+
+// The result of a health check.
+type HealthCheckResult struct {
+	// The name of the check.
+	Name string `json:"name" xml:"name"`
+	// If the check passed.
+	Status HealthStatus `json:"status" xml:"status"`
+	// How long the check took in milliseconds.
+	Latency float64 `json:"latencyMs" xml:"latencyMs"`
+	// Why the check is down.
+	Error string `json:"error,omitempty" xml:"error,omitempty"`
+}
+
+// Serves the result of running the checks at the given pattern.
+func (site *Site) ServeHealth(pattern string, checks ...HealthCheck) {
+	site.serveHealth(pattern, "Health", false, checks)
+}
+
+// Serves the result of running the checks at the given pattern for a liveness probe. A liveness
+// probe should only fail when the process needs to be restarted, so the checks should not
+// include dependencies shared with other processes.
+func (site *Site) ServeLiveness(pattern string, checks ...HealthCheck) {
+	site.serveHealth(pattern, "Liveness", false, checks)
+}
+
+// Serves the result of running the checks at the given pattern for a readiness probe. Once the
+// site is shutting down the readiness is down without running the checks, so traffic is sent
+// elsewhere while in-flight requests finish.
+func (site *Site) ServeReadiness(pattern string, checks ...HealthCheck) {
+	site.serveHealth(pattern, "Readiness", true, checks)
+}
+
+func (site *Site) serveHealth(pattern string, summary string, readiness bool, checks []HealthCheck) {
+	site.Get(pattern, func(ctx context.Context, scope *deps.Scope) Health {
+		if readiness && site.servers.isShuttingDown() {
+			return Health{
+				Status: HealthStatusDown,
+				Checks: []HealthCheckResult{{Name: "shutdown", Status: HealthStatusDown, Error: "the service is shutting down"}},
+			}
+		}
+		return runHealthChecks(ctx, scope, checks)
+	}, api.Operation{Summary: summary})
+}
+
+// Runs the checks in parallel and returns their results.
+func runHealthChecks(ctx context.Context, scope *deps.Scope, checks []HealthCheck) Health {
+	health := Health{
+		Status: HealthStatusUp,
+		Checks: make([]HealthCheckResult, len(checks)),
+	}
+
+	wait := sync.WaitGroup{}
+	for i, check := range checks {
+		wait.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wait.Done()
+			health.Checks[i] = runHealthCheck(ctx, scope, check)
+		}(i, check)
+	}
+	wait.Wait()
+
+	for _, result := range health.Checks {
+		if result.Status != HealthStatusUp {
+			health.Status = HealthStatusDown
+		}
+	}
+
+	return health
+}
+
+// Runs the check in its own scope and returns its result, or a timeout if it takes too long.
+func runHealthCheck(ctx context.Context, scope *deps.Scope, check HealthCheck) HealthCheckResult {
+	timeout := check.Timeout
+	if timeout == 0 {
+		timeout = DefaultHealthTimeout
+	}
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	checkScope := scope.Spawn()
+	deps.SetScoped(checkScope, &checkCtx)
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				done <- fmt.Errorf("panic: %v", recovered)
+			}
+			checkScope.Free()
+		}()
+		result, err := checkScope.Invoke(check.Check)
+		if err == nil {
+			err = result.Err()
+		}
+		done <- err
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-checkCtx.Done():
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+
+	result := HealthCheckResult{
+		Name:    check.Name,
+		Status:  HealthStatusUp,
+		Latency: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = HealthStatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
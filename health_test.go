@@ -0,0 +1,106 @@
+package rez
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ClickerMonkey/deps"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type testDatabase struct {
+	up bool
+}
+
+func TestHealth(t *testing.T) {
+	site := New(chi.NewRouter())
+	site.Scope = deps.New()
+	db := &testDatabase{up: true}
+	deps.SetScoped(site.Scope, db)
+
+	checks := []HealthCheck{{
+		Name: "database",
+		Check: func(db *testDatabase) error {
+			if !db.up {
+				return errors.New("database unreachable")
+			}
+			return nil
+		},
+	}, {
+		Name:    "slow",
+		Timeout: 20 * time.Millisecond,
+		Check: func(ctx context.Context) {
+			<-ctx.Done()
+		},
+	}, {
+		Name:  "panics",
+		Check: func() { panic("oops") },
+	}}
+
+	site.ServeHealth("/health", checks[0])
+	site.ServeLiveness("/live")
+	site.ServeReadiness("/ready", checks...)
+
+	get := func(path string) (int, Health) {
+		w := httptest.NewRecorder()
+		site.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		health := Health{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &health), w.Body.String())
+		return w.Code, health
+	}
+
+	code, health := get("/health")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, HealthStatusUp, health.Status)
+	assert.Len(t, health.Checks, 1)
+	assert.Equal(t, "database", health.Checks[0].Name)
+	assert.Equal(t, HealthStatusUp, health.Checks[0].Status)
+
+	code, health = get("/live")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, HealthStatusUp, health.Status)
+	assert.Len(t, health.Checks, 0)
+
+	db.up = false
+	code, health = get("/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, HealthStatusDown, health.Status)
+	assert.Equal(t, []HealthCheckResult{
+		{Name: "database", Status: HealthStatusDown, Error: "database unreachable"},
+		{Name: "slow", Status: HealthStatusDown, Error: "timed out after 20ms"},
+		{Name: "panics", Status: HealthStatusDown, Error: "panic: oops"},
+	}, withoutLatency(health.Checks))
+	assert.GreaterOrEqual(t, health.Checks[1].Latency, 20.0)
+
+	db.up = true
+	code, _ = get("/health")
+	assert.Equal(t, http.StatusOK, code)
+
+	assert.NoError(t, site.Shutdown(context.Background()))
+	code, health = get("/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, []HealthCheckResult{
+		{Name: "shutdown", Status: HealthStatusDown, Error: "the service is shutting down"},
+	}, health.Checks)
+
+	doc := site.BuildDocument()
+	op := doc.Paths["/ready"].Get
+	assert.Equal(t, "Readiness", op.Summary)
+	assert.ElementsMatch(t, []string{"200", "500", "503"}, keys(op.Responses))
+	assert.Contains(t, toJSON(doc), `"enum":["up","down"]`)
+}
+
+func withoutLatency(results []HealthCheckResult) []HealthCheckResult {
+	copied := make([]HealthCheckResult, len(results))
+	for i, result := range results {
+		result.Latency = 0
+		copied[i] = result
+	}
+	return copied
+}
@@ -0,0 +1,178 @@
+package rez
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/ClickerMonkey/deps"
+	"github.com/ClickerMonkey/rez/api"
+)
+
+// The error sent when the request body is larger than the body limit of the router.
+type RequestEntityTooLarge struct {
+	// The maximum number of bytes of a request body.
+	Limit int64 `json:"limit" xml:"limit"`
+}
+
+var _ error = RequestEntityTooLarge{}
+var _ HasStatus = RequestEntityTooLarge{}
+
+var requestEntityTooLargeType = reflect.TypeOf(RequestEntityTooLarge{})
+
+func (rtl RequestEntityTooLarge) Error() string {
+	return fmt.Sprintf("request body exceeds the limit of %d bytes", rtl.Limit)
+}
+func (rtl RequestEntityTooLarge) HTTPStatus() int {
+	return http.StatusRequestEntityTooLarge
+}
+func (rtl RequestEntityTooLarge) HTTPStatuses() []int {
+	return []int{http.StatusRequestEntityTooLarge}
+}
+func (rtl RequestEntityTooLarge) APIDescription() string {
+	return "The request body is larger than the limit."
+}
+
+// Options for decoding request bodies. The zero value has no limits.
+type DecodeOptions struct {
+	// If JSON bodies with properties which aren't fields of the body type fail validation.
+	// This applies to the built in JSON decoder and not decoders given to RegisterCodec.
+	DisallowUnknownFields bool
+	// The maximum number of nested arrays and objects in a JSON body, zero for no limit.
+	MaxDepth int
+	// The maximum number of items in an array in a JSON body, zero for no limit.
+	MaxArrayLength int
+}
+
+// Limits the request body to the body limit of the router. If the request says its body
+// is larger than the limit the error is returned without reading it.
+func limitBody(r *http.Request, router Router, scope *deps.Scope) error {
+	limit := router.GetBodyLimit()
+	if limit <= 0 {
+		return nil
+	}
+	if r.ContentLength > limit {
+		return RequestEntityTooLarge{Limit: limit}
+	}
+	var w http.ResponseWriter
+	if response, _ := deps.GetScoped[http.ResponseWriter](scope); response != nil {
+		w = *response
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	return nil
+}
+
+// Returns whether the content type is decoded as JSON.
+func isJSONContentType(contentType api.ContentType) bool {
+	return contentType == api.ContentTypeNone || strings.Contains(string(contentType), "json")
+}
+
+func decodeJSONStrict(r io.Reader, target any) error {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(target)
+}
+
+// The error returned when a JSON body exceeds the limits of the decode options.
+type jsonLimitError struct {
+	rule    ValidationRule
+	message string
+}
+
+func (e jsonLimitError) Error() string {
+	return e.message
+}
+
+// A reader of a JSON body which fails as soon as the body nests arrays and objects
+// too deeply or has an array with too many items, before the decoder allocates them.
+type jsonLimitReader struct {
+	reader   io.Reader
+	options  DecodeOptions
+	items    []int
+	inString bool
+	escaped  bool
+	err      error
+}
+
+// Returns the reader of the body which enforces the limits of the options, if any.
+func limitJSON(reader io.Reader, options DecodeOptions) io.Reader {
+	if options.MaxDepth <= 0 && options.MaxArrayLength <= 0 {
+		return reader
+	}
+	return &jsonLimitReader{reader: reader, options: options}
+}
+
+func (l *jsonLimitReader) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	n, err := l.reader.Read(p)
+	for i := 0; i < n; i++ {
+		if l.err = l.scan(p[i]); l.err != nil {
+			return i, l.err
+		}
+	}
+	return n, err
+}
+
+// Scans the next byte of the body. The items of each open array are counted and
+// objects are -1.
+func (l *jsonLimitReader) scan(c byte) error {
+	if l.inString {
+		switch {
+		case l.escaped:
+			l.escaped = false
+		case c == '\\':
+			l.escaped = true
+		case c == '"':
+			l.inString = false
+		}
+		return nil
+	}
+
+	switch c {
+	case '"':
+		l.inString = true
+	case '{', '[':
+		items := -1
+		if c == '[' {
+			items = 1
+		}
+		l.items = append(l.items, items)
+		if l.options.MaxDepth > 0 && len(l.items) > l.options.MaxDepth {
+			return jsonLimitError{
+				rule:    ValidationRuleMaxDepth,
+				message: fmt.Sprintf("exceeds the maximum depth of %d", l.options.MaxDepth),
+			}
+		}
+	case '}', ']':
+		if len(l.items) > 0 {
+			l.items = l.items[:len(l.items)-1]
+		}
+	case ',':
+		last := len(l.items) - 1
+		if last >= 0 && l.items[last] > 0 {
+			l.items[last]++
+			if l.options.MaxArrayLength > 0 && l.items[last] > l.options.MaxArrayLength {
+				return jsonLimitError{
+					rule:    ValidationRuleMaxItems,
+					message: fmt.Sprintf("an array exceeds the maximum length of %d", l.options.MaxArrayLength),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the error to send when reading the body failed because it was larger than the limit.
+func bodyTooLarge(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return RequestEntityTooLarge{Limit: maxBytesErr.Limit}
+	}
+	return nil
+}
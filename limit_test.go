@@ -0,0 +1,139 @@
+package rez
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type limitedBody struct {
+	Name string         `json:"name"`
+	Tags []string       `json:"tags"`
+	Meta map[string]any `json:"meta"`
+}
+
+func TestBodyLimits(t *testing.T) {
+	site := New(chi.NewRouter())
+
+	site.Post("/open", func(b Body[limitedBody]) {})
+	site.Group(func(r Router) {
+		r.SetBodyLimit(64)
+		r.SetDecodeOptions(DecodeOptions{
+			DisallowUnknownFields: true,
+			MaxDepth:              2,
+			MaxArrayLength:        3,
+		})
+		r.Route("/limited", func(r Router) {
+			r.Post("/", func(b Body[limitedBody]) {})
+		})
+	})
+
+	serve := func(request *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		site.router.ServeHTTP(w, request)
+		return w
+	}
+
+	large := `{"name":"` + strings.Repeat("a", 100) + `"}`
+	chunked := httptest.NewRequest(http.MethodPost, "/limited/", strings.NewReader(large))
+	chunked.ContentLength = -1
+
+	tests := []struct {
+		name    string
+		request *http.Request
+		status  int
+		body    string
+	}{
+		{
+			name:    "open",
+			request: httptest.NewRequest(http.MethodPost, "/open", strings.NewReader(`{"nope":[[[1,2,3,4]]],"name":"`+strings.Repeat("a", 100)+`"}`)),
+			status:  http.StatusOK,
+		},
+		{
+			name:    "within limits",
+			request: httptest.NewRequest(http.MethodPost, "/limited/", strings.NewReader(`{"name":"[[,,\"]]","tags":["a","b","c"],"meta":{}}`)),
+			status:  http.StatusOK,
+		},
+		{
+			name:    "content length",
+			request: httptest.NewRequest(http.MethodPost, "/limited/", strings.NewReader(large)),
+			status:  http.StatusRequestEntityTooLarge,
+			body:    `{"limit":64}`,
+		},
+		{
+			name:    "chunked",
+			request: chunked,
+			status:  http.StatusRequestEntityTooLarge,
+			body:    `{"limit":64}`,
+		},
+		{
+			name:    "unknown field",
+			request: httptest.NewRequest(http.MethodPost, "/limited/", strings.NewReader(`{"nope":1}`)),
+			status:  http.StatusBadRequest,
+			body:    `{"validations":[{"path":["body"],"rule":"unknownField","message":"unknown field \"nope\""}]}`,
+		},
+		{
+			name:    "depth",
+			request: httptest.NewRequest(http.MethodPost, "/limited/", strings.NewReader(`{"meta":{"a":[]}}`)),
+			status:  http.StatusBadRequest,
+			body:    `{"validations":[{"path":["body"],"rule":"maxDepth","message":"exceeds the maximum depth of 2"}]}`,
+		},
+		{
+			name:    "array length",
+			request: httptest.NewRequest(http.MethodPost, "/limited/", strings.NewReader(`{"tags":["a","b","c","d"]}`)),
+			status:  http.StatusBadRequest,
+			body:    `{"validations":[{"path":["body"],"rule":"maxItems","message":"an array exceeds the maximum length of 3"}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(test.request)
+			assert.Equal(t, test.status, w.Code, w.Body.String())
+			if test.body != "" {
+				assert.Equal(t, test.body, strings.TrimSpace(w.Body.String()))
+			}
+		})
+	}
+
+	doc := site.Open.Build()
+
+	assert.Nil(t, doc.Paths["/open"].Post.Responses["413"])
+	assert.NotNil(t, doc.Paths["/limited/"].Post.Responses["413"])
+}
+
+func TestBodyLimitsWithMiddleware(t *testing.T) {
+	site := New(chi.NewRouter())
+	site.Use(func(next MiddlewareNext) {
+		next()
+	})
+
+	var routed Router
+	site.Group(func(r Router) {
+		r.SetBodyLimit(16)
+		r.SetDecodeOptions(DecodeOptions{DisallowUnknownFields: true})
+		r.Post("/limited", func(b Body[limitedBody], router Router) {
+			routed = router
+		})
+	})
+
+	serve := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		site.router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/limited", strings.NewReader(body)))
+		return w
+	}
+
+	w := serve(`{"name":"` + strings.Repeat("a", 100) + `"}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code, w.Body.String())
+
+	w = serve(`{"nope":1}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	w = serve(`{"name":"a"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, int64(16), routed.GetBodyLimit())
+}
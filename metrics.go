@@ -0,0 +1,244 @@
+package rez
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ClickerMonkey/rez/api"
+	"github.com/go-chi/chi/v5"
+)
+
+// The upper bounds in seconds of the buckets of the request duration histogram.
+var MetricsDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// The upper bounds in bytes of the buckets of the response size histogram.
+var MetricsSizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+
+// The content type of the Prometheus text exposition format.
+const ContentTypePrometheus = "text/plain; version=0.0.4; charset=utf-8"
+
+// Enables or disables recording metrics of the requests handled by operations. This affects
+// the whole site, including routers created before this call. Requests are labelled by their
+// method, route pattern, operation id, and status so the number of series doesn't grow with
+// the URLs requested. The buckets are taken from MetricsDurationBuckets and MetricsSizeBuckets
+// when metrics are first enabled.
+func (site *Site) EnableMetrics(enabled bool) {
+	site.metrics.mutex.Lock()
+	defer site.metrics.mutex.Unlock()
+
+	site.metrics.recording = enabled
+	if enabled && site.metrics.requests == nil {
+		site.metrics.durationBuckets = append([]float64{}, MetricsDurationBuckets...)
+		site.metrics.sizeBuckets = append([]float64{}, MetricsSizeBuckets...)
+		site.metrics.requests = make(map[metricKey]*metricRequests)
+		site.metrics.inFlight = make(map[metricKey]int64)
+	}
+}
+
+// Enables metrics and serves them in the Prometheus text format at the given pattern.
+// The endpoint is not documented or measured.
+func (site *Site) ServeMetrics(pattern string) {
+	site.EnableMetrics(true)
+
+	site.router.Get(pattern, func(w http.ResponseWriter, request *http.Request) {
+		w.Header().Set("Content-Type", ContentTypePrometheus)
+		w.WriteHeader(http.StatusOK)
+		site.internalError(site.WriteMetrics(w))
+	})
+}
+
+// Writes the metrics of the site in the Prometheus text format:
+//
+//	rez_http_requests_total             counter   the requests handled
+//	rez_http_request_duration_seconds   histogram how long requests took
+//	rez_http_response_size_bytes        histogram how large the response bodies were
+//	rez_http_requests_in_flight         gauge     the requests being handled
+func (site *Site) WriteMetrics(w io.Writer) error {
+	return site.metrics.write(w)
+}
+
+// The metrics of requests handled by operations, shared by a site and its sub routers.
+type siteMetrics struct {
+	mutex           sync.Mutex
+	recording       bool
+	durationBuckets []float64
+	sizeBuckets     []float64
+	requests        map[metricKey]*metricRequests
+	inFlight        map[metricKey]int64
+}
+
+// The labels of a metric. In flight requests have no status.
+type metricKey struct {
+	method    string
+	route     string
+	operation string
+	status    int
+}
+
+type metricRequests struct {
+	count    uint64
+	duration metricHistogram
+	size     metricHistogram
+}
+
+type metricHistogram struct {
+	counts []uint64
+	sum    float64
+}
+
+func (h *metricHistogram) observe(buckets []float64, value float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(buckets))
+	}
+	for i, bound := range buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+}
+
+func (m *siteMetrics) enabled() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.recording
+}
+
+// Marks the request as in flight and returns its labels.
+func (m *siteMetrics) start(op *api.Operation, request *http.Request) metricKey {
+	key := metricKey{
+		method:    request.Method,
+		route:     request.URL.Path,
+		operation: op.OperationID,
+	}
+	if route := chi.RouteContext(request.Context()); route != nil && route.RoutePattern() != "" {
+		key.route = route.RoutePattern()
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.inFlight[key]++
+
+	return key
+}
+
+// Records the request started with the given labels.
+func (m *siteMetrics) finish(key metricKey, w *statusRecorder, duration time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.inFlight[key]--
+
+	key.status = w.status
+	if key.status == 0 {
+		key.status = http.StatusOK
+	}
+	requests := m.requests[key]
+	if requests == nil {
+		requests = &metricRequests{}
+		m.requests[key] = requests
+	}
+	requests.count++
+	requests.duration.observe(m.durationBuckets, duration.Seconds())
+	requests.size.observe(m.sizeBuckets, float64(w.size))
+}
+
+func (m *siteMetrics) write(w io.Writer) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	out := bufio.NewWriter(w)
+
+	keys := make([]metricKey, 0, len(m.requests))
+	for key := range m.requests {
+		keys = append(keys, key)
+	}
+	sortMetricKeys(keys)
+
+	writeMetricHeader(out, "rez_http_requests_total", "counter", "The number of requests handled by each operation.")
+	for _, key := range keys {
+		fmt.Fprintf(out, "rez_http_requests_total{%s} %d\n", key.labels(), m.requests[key].count)
+	}
+
+	writeMetricHeader(out, "rez_http_request_duration_seconds", "histogram", "How long requests took to handle in seconds.")
+	for _, key := range keys {
+		requests := m.requests[key]
+		writeMetricHistogram(out, "rez_http_request_duration_seconds", key.labels(), m.durationBuckets, requests.duration, requests.count)
+	}
+
+	writeMetricHeader(out, "rez_http_response_size_bytes", "histogram", "The size of the response bodies in bytes.")
+	for _, key := range keys {
+		requests := m.requests[key]
+		writeMetricHistogram(out, "rez_http_response_size_bytes", key.labels(), m.sizeBuckets, requests.size, requests.count)
+	}
+
+	inFlight := make([]metricKey, 0, len(m.inFlight))
+	for key := range m.inFlight {
+		inFlight = append(inFlight, key)
+	}
+	sortMetricKeys(inFlight)
+
+	writeMetricHeader(out, "rez_http_requests_in_flight", "gauge", "The number of requests being handled by each operation.")
+	for _, key := range inFlight {
+		fmt.Fprintf(out, "rez_http_requests_in_flight{%s} %d\n", key.labels(), m.inFlight[key])
+	}
+
+	return out.Flush()
+}
+
+func writeMetricHeader(out io.Writer, name string, kind string, help string) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func writeMetricHistogram(out io.Writer, name string, labels string, buckets []float64, histogram metricHistogram, count uint64) {
+	for i, bound := range buckets {
+		fmt.Fprintf(out, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatMetricFloat(bound), histogram.counts[i])
+	}
+	fmt.Fprintf(out, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, count)
+	fmt.Fprintf(out, "%s_sum{%s} %s\n", name, labels, formatMetricFloat(histogram.sum))
+	fmt.Fprintf(out, "%s_count{%s} %d\n", name, labels, count)
+}
+
+func formatMetricFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Returns the labels of the key in the exposition format.
+func (key metricKey) labels() string {
+	labels := fmt.Sprintf(`method="%s",route="%s",operation="%s"`,
+		metricLabelEscaper.Replace(key.method),
+		metricLabelEscaper.Replace(key.route),
+		metricLabelEscaper.Replace(key.operation),
+	)
+	if key.status != 0 {
+		labels += `,status="` + strconv.Itoa(key.status) + `"`
+	}
+	return labels
+}
+
+func sortMetricKeys(keys []metricKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		if a.operation != b.operation {
+			return a.operation < b.operation
+		}
+		return a.status < b.status
+	})
+}
@@ -0,0 +1,88 @@
+package rez
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ClickerMonkey/rez/api"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	site := New(chi.NewRouter())
+
+	site.Get("/tasks/{id}", func(p Path[struct{ ID int }]) (string, *NotFound[string]) {
+		if p.Value.ID > 10 {
+			return "", NewNotFound("no task")
+		}
+		return "task", nil
+	}, api.Operation{OperationID: "getTask"})
+	site.Route("/v2", func(r Router) {
+		r.Post("/tasks", func() string {
+			return "created"
+		})
+	})
+
+	get := func(method string, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		site.router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	get(http.MethodGet, "/tasks/1")
+	assert.Equal(t, "", site.buildMetrics(t))
+
+	site.ServeMetrics("/metrics")
+
+	get(http.MethodGet, "/tasks/1")
+	get(http.MethodGet, "/tasks/2")
+	get(http.MethodGet, "/tasks/20")
+	get(http.MethodPost, "/v2/tasks")
+
+	res := get(http.MethodGet, "/metrics")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, ContentTypePrometheus, res.Header().Get("Content-Type"))
+
+	text := res.Body.String()
+	for _, line := range []string{
+		`# TYPE rez_http_requests_total counter`,
+		`rez_http_requests_total{method="GET",route="/tasks/{id}",operation="getTask",status="200"} 2`,
+		`rez_http_requests_total{method="GET",route="/tasks/{id}",operation="getTask",status="404"} 1`,
+		`rez_http_requests_total{method="POST",route="/v2/tasks",operation="",status="200"} 1`,
+		`# TYPE rez_http_request_duration_seconds histogram`,
+		`rez_http_request_duration_seconds_bucket{method="GET",route="/tasks/{id}",operation="getTask",status="200",le="+Inf"} 2`,
+		`rez_http_request_duration_seconds_count{method="GET",route="/tasks/{id}",operation="getTask",status="200"} 2`,
+		`# TYPE rez_http_response_size_bytes histogram`,
+		`rez_http_response_size_bytes_bucket{method="GET",route="/tasks/{id}",operation="getTask",status="200",le="100"} 2`,
+		`rez_http_response_size_bytes_sum{method="GET",route="/tasks/{id}",operation="getTask",status="200"} 14`,
+		`# TYPE rez_http_requests_in_flight gauge`,
+		`rez_http_requests_in_flight{method="GET",route="/tasks/{id}",operation="getTask"} 0`,
+	} {
+		assert.Contains(t, text, line+"\n")
+	}
+	assert.NotContains(t, text, `route="/metrics"`)
+	assert.NotContains(t, text, `/tasks/1"`)
+
+	key := metricKey{method: "GET", route: `/a"b\c`, status: 200}
+	assert.Equal(t, `method="GET",route="/a\"b\\c",operation="",status="200"`, key.labels())
+}
+
+func (site *Site) buildMetrics(t *testing.T) string {
+	out := &strings.Builder{}
+	assert.NoError(t, site.WriteMetrics(out))
+	return strings.Join(metricSamples(out.String()), "\n")
+}
+
+// Returns the lines which are not comments.
+func metricSamples(text string) []string {
+	samples := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			samples = append(samples, line)
+		}
+	}
+	return samples
+}
@@ -0,0 +1,390 @@
+package rez
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ClickerMonkey/rez/api"
+)
+
+// A function which writes the encoded value to the writer.
+type Encoder func(w io.Writer, value any) error
+
+// A function which reads the encoded value from the reader into the target pointer.
+type Decoder func(r io.Reader, target any) error
+
+// The error sent when none of the content types a response can be sent
+// as are acceptable to the client based on the Accept header.
+type NotAcceptable struct {
+	Accept    string   `json:"accept" xml:"accept"`
+	Available []string `json:"available" xml:"available"`
+}
+
+var _ error = NotAcceptable{}
+var _ HasStatus = NotAcceptable{}
+
+var notAcceptableType = reflect.TypeOf(NotAcceptable{})
+
+func (na NotAcceptable) Error() string {
+	return fmt.Sprintf("none of the available content types (%s) are acceptable", strings.Join(na.Available, ", "))
+}
+func (na NotAcceptable) HTTPStatus() int {
+	return http.StatusNotAcceptable
+}
+func (na NotAcceptable) HTTPStatuses() []int {
+	return []int{http.StatusNotAcceptable}
+}
+func (na NotAcceptable) APIDescription() string {
+	return "None of the content types of the response are acceptable."
+}
+
+// Returns whether a response of the operation can be sent as more than one content
+// type, which is chosen by the Accept header of the request.
+func isNegotiated(op *api.Operation) bool {
+	for _, response := range op.Responses {
+		if response != nil && len(response.Content) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// The error sent when the request body has a content type there is no decoder for.
+type UnsupportedMediaType struct {
+	ContentType string `json:"contentType" xml:"contentType"`
+}
+
+var _ error = UnsupportedMediaType{}
+var _ HasStatus = UnsupportedMediaType{}
+
+func (umt UnsupportedMediaType) Error() string {
+	return fmt.Sprintf("Content-Type %s not supported", umt.ContentType)
+}
+func (umt UnsupportedMediaType) HTTPStatus() int {
+	return http.StatusUnsupportedMediaType
+}
+func (umt UnsupportedMediaType) HTTPStatuses() []int {
+	return []int{http.StatusUnsupportedMediaType}
+}
+
+// A registered content type and how to decode and encode values of it.
+type codec struct {
+	contentType api.ContentType
+	decoder     Decoder
+	encoder     Encoder
+}
+
+// The content types registered on a site and all of its sub routers.
+type codecs struct {
+	list []codec
+}
+
+// Adds or replaces the codec for the given content type.
+func (c *codecs) set(next codec) {
+	for i := range c.list {
+		if c.list[i].contentType == next.contentType {
+			c.list[i] = next
+			return
+		}
+	}
+	c.list = append(c.list, next)
+}
+
+// Gets the codec for the given content type, if any.
+func (c *codecs) get(contentType api.ContentType) *codec {
+	for i := range c.list {
+		if c.list[i].contentType == contentType {
+			return &c.list[i]
+		}
+	}
+	return nil
+}
+
+func encodeJSON(w io.Writer, value any) error {
+	return json.NewEncoder(w).Encode(value)
+}
+
+func encodeXML(w io.Writer, value any) error {
+	return xml.NewEncoder(w).Encode(value)
+}
+
+func decodeJSON(r io.Reader, target any) error {
+	return json.NewDecoder(r).Decode(target)
+}
+
+func decodeXML(r io.Reader, target any) error {
+	return xml.NewDecoder(r).Decode(target)
+}
+
+func encodeText(w io.Writer, value any) error {
+	if marshaller, ok := value.(encoding.TextMarshaler); ok {
+		text, err := marshaller.MarshalText()
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(text)
+		return err
+	} else if bytes, ok := value.([]byte); ok {
+		_, err := w.Write(bytes)
+		return err
+	} else {
+		_, err := io.WriteString(w, toString(value))
+		return err
+	}
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// Returns whether the type has a natural text/plain representation.
+func isTextType(typ reflect.Type) bool {
+	if typ.Implements(textMarshalerType) || reflect.PointerTo(typ).Implements(textMarshalerType) {
+		return true
+	}
+	switch typ.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return typ.Elem().Kind() == reflect.Uint8
+	}
+	return false
+}
+
+var hasContentTypeType = reflect.TypeOf((*HasContentType)(nil)).Elem()
+var hasContentTypesType = reflect.TypeOf((*HasContentTypes)(nil)).Elem()
+
+func toContentTypes(contentTypes []string) []api.ContentType {
+	converted := make([]api.ContentType, len(contentTypes))
+	for i, contentType := range contentTypes {
+		converted[i] = api.ContentType(contentType)
+	}
+	return converted
+}
+
+func fromContentTypes(contentTypes []api.ContentType) []string {
+	converted := make([]string, len(contentTypes))
+	for i, contentType := range contentTypes {
+		converted[i] = string(contentType)
+	}
+	return converted
+}
+
+// Returns the content types the given response type can be sent as, in order of preference.
+func (site *Site) responseContentTypes(typ reflect.Type) []api.ContentType {
+	typ = getConcrete(typ)
+	if typ.Kind() != reflect.Interface && reflect.PointerTo(typ).Implements(hasContentTypesType) {
+		if has, ok := reflect.New(typ).Interface().(HasContentTypes); ok {
+			return toContentTypes(has.HTTPContentTypes())
+		}
+	}
+	if typ.Kind() != reflect.Interface && reflect.PointerTo(typ).Implements(hasContentTypeType) {
+		if has, ok := reflect.New(typ).Interface().(HasContentType); ok {
+			return []api.ContentType{api.ContentType(has.HTTPContentType())}
+		}
+	}
+
+	contentTypes := make([]api.ContentType, 0, len(site.codecs.list)+3)
+	if site.ServeJSON {
+		contentTypes = append(contentTypes, api.ContentTypeJSON)
+	}
+	if site.ServeXML {
+		contentTypes = append(contentTypes, api.ContentTypeXML)
+	}
+	for _, c := range site.codecs.list {
+		if c.encoder != nil && !hasContentType(contentTypes, c.contentType) {
+			contentTypes = append(contentTypes, c.contentType)
+		}
+	}
+	if typ.Kind() != reflect.Interface && isTextType(typ) && !hasContentType(contentTypes, api.ContentTypeText) {
+		contentTypes = append(contentTypes, api.ContentTypeText)
+	}
+	if len(contentTypes) == 0 {
+		contentTypes = append(contentTypes, api.ContentTypeJSON)
+	}
+	return contentTypes
+}
+
+// Returns the content types a request body with the given schema can be sent as, in order of preference.
+func (site *Site) requestContentTypes(schema *api.Schema) []api.ContentType {
+	if contentType := schema.ContentType(); contentType != api.ContentTypeJSON {
+		return []api.ContentType{contentType}
+	}
+
+	contentTypes := make([]api.ContentType, 0, len(site.codecs.list)+2)
+	if site.ServeJSON {
+		contentTypes = append(contentTypes, api.ContentTypeJSON)
+	}
+	if site.ServeXML {
+		contentTypes = append(contentTypes, api.ContentTypeXML)
+	}
+	for _, c := range site.codecs.list {
+		if c.decoder != nil && !hasContentType(contentTypes, c.contentType) {
+			contentTypes = append(contentTypes, c.contentType)
+		}
+	}
+	if len(contentTypes) == 0 {
+		contentTypes = append(contentTypes, api.ContentTypeJSON)
+	}
+	return contentTypes
+}
+
+func hasContentType(contentTypes []api.ContentType, contentType api.ContentType) bool {
+	for _, ct := range contentTypes {
+		if ct == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// Registers how to decode request bodies and encode responses of the given content type.
+// The decoder or encoder can be nil if the content type is only accepted or only sent.
+// Responses will be sent with this content type when the request's Accept header prefers it
+// and the operations on this site will document it as a possible request and response content type.
+// This affects the whole site, including routers created before this call.
+func (site *Site) RegisterCodec(contentType api.ContentType, decoder Decoder, encoder Encoder) {
+	site.codecs.set(codec{contentType: contentType, decoder: decoder, encoder: encoder})
+}
+
+// Registers an encoder for the given content type. This is the same as RegisterCodec
+// but keeps any decoder already registered for the content type.
+func (site *Site) RegisterEncoder(contentType api.ContentType, encoder Encoder) {
+	var decoder Decoder
+	if existing := site.codecs.get(contentType); existing != nil {
+		decoder = existing.decoder
+	}
+	site.RegisterCodec(contentType, decoder, encoder)
+}
+
+// Returns the decoder for the given content type. Registered decoders are
+// preferred, otherwise JSON and XML are matched by their suffix. A missing
+// content type is decoded as JSON. If there is no decoder nil is returned.
+func (site *Site) GetDecoder(contentType api.ContentType) Decoder {
+	if c := site.codecs.get(contentType); c != nil && c.decoder != nil {
+		return c.decoder
+	}
+	ct := string(contentType)
+	switch {
+	case isJSONContentType(contentType) && site.decodeOptions.DisallowUnknownFields:
+		return decodeJSONStrict
+	case contentType == api.ContentTypeNone:
+		return decodeJSON
+	case strings.Contains(ct, "json"):
+		return decodeJSON
+	case strings.Contains(ct, "xml"):
+		return decodeXML
+	}
+	return nil
+}
+
+// Returns the encoder for the given content type. Registered encoders are
+// preferred, otherwise JSON, XML, and text are matched by their suffix or prefix.
+// If there is no encoder nil is returned.
+func (site *Site) GetEncoder(contentType api.ContentType) Encoder {
+	if c := site.codecs.get(contentType); c != nil && c.encoder != nil {
+		return c.encoder
+	}
+	ct := string(contentType)
+	switch {
+	case strings.Contains(ct, "xml"):
+		return encodeXML
+	case strings.Contains(ct, "json"):
+		return encodeJSON
+	case strings.Contains(ct, "text"):
+		return encodeText
+	}
+	return nil
+}
+
+// A media range in an Accept header.
+type acceptRange struct {
+	mediaType string
+	subType   string
+	quality   float64
+}
+
+// Parses the Accept header into its media ranges.
+func parseAccept(header string) []acceptRange {
+	ranges := make([]acceptRange, 0)
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "" {
+			continue
+		}
+		types := strings.SplitN(mediaType, "/", 2)
+		if len(types) != 2 {
+			continue
+		}
+		r := acceptRange{mediaType: types[0], subType: types[1], quality: 1}
+		for _, param := range params[1:] {
+			keyValue := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(keyValue) == 2 && strings.ToLower(keyValue[0]) == "q" {
+				if q, err := strconv.ParseFloat(keyValue[1], 64); err == nil {
+					r.quality = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// Returns the quality the client assigned to the content type, or -1 if
+// the client did not list a range which matches the content type.
+func acceptQuality(ranges []acceptRange, contentType api.ContentType) float64 {
+	mediaType := strings.SplitN(string(contentType), ";", 2)[0]
+	types := strings.SplitN(strings.ToLower(strings.TrimSpace(mediaType)), "/", 2)
+	if len(types) != 2 {
+		return -1
+	}
+	quality := float64(-1)
+	specificity := -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.mediaType == types[0] && r.subType == types[1]:
+			s = 2
+		case r.mediaType == types[0] && r.subType == "*":
+			s = 1
+		case r.mediaType == "*" && r.subType == "*":
+			s = 0
+		}
+		if s > specificity {
+			specificity = s
+			quality = r.quality
+		}
+	}
+	return quality
+}
+
+// Chooses the content type to send based on the Accept header. The first offered content type
+// with the highest quality is returned. If no content type is acceptable false is returned.
+func negotiate(accept string, offers []api.ContentType) (api.ContentType, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0], true
+	}
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return offers[0], true
+	}
+	best := api.ContentTypeNone
+	bestQuality := float64(0)
+	for _, offer := range offers {
+		quality := acceptQuality(ranges, offer)
+		if quality > bestQuality {
+			best = offer
+			bestQuality = quality
+		}
+	}
+	return best, best != api.ContentTypeNone
+}
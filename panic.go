@@ -0,0 +1,82 @@
+package rez
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime/debug"
+
+	"github.com/ClickerMonkey/deps"
+	"github.com/ClickerMonkey/rez/api"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// A function which is given the value recovered from a panic in a route or middleware, the
+// stack trace of the panic, the request, the scope of the request (if it was created yet), and
+// the operation (routes only). The client is sent an InternalError with the given id.
+type PanicHandler = func(recovered any, stack []byte, request *http.Request, scope *deps.Scope, op *api.Operation, id string)
+
+// The 500 response sent when a route or middleware panics. It has none of the details
+// of the panic, only an id to find them with.
+type InternalError struct {
+	// A message for the client.
+	Message string `json:"message" xml:"message"`
+	// The id of the panic, the chi request id if there is one.
+	ID string `json:"id" xml:"id"`
+}
+
+var _ error = InternalError{}
+var _ HasStatus = InternalError{}
+
+func (ie InternalError) Error() string {
+	return ie.Message + " (" + ie.ID + ")"
+}
+func (ie InternalError) HTTPStatus() int {
+	return http.StatusInternalServerError
+}
+func (ie InternalError) HTTPStatuses() []int {
+	return []int{http.StatusInternalServerError}
+}
+func (ie InternalError) APIDescription() string {
+	return "An unexpected error occurred."
+}
+
+var internalErrorType = reflect.TypeOf(InternalError{})
+
+// Sets the handler which is given the panics of routes and middleware in this router and
+// sub routers created after this is set. Without a handler panics are given to the
+// InternalErrorHandler with their stack trace.
+func (site *Site) SetPanicHandler(handler PanicHandler) {
+	site.panicHandler = handler
+}
+
+// Handles a recovered panic
+func (site *Site) handlePanic(recovered any, response http.ResponseWriter, request *http.Request, scope *deps.Scope, op *api.Operation) {
+	if recovered == http.ErrAbortHandler {
+		panic(recovered)
+	}
+
+	stack := debug.Stack()
+	id := middleware.GetReqID(request.Context())
+	if id == "" {
+		id = newPanicID()
+	}
+
+	if site.panicHandler != nil {
+		site.panicHandler(recovered, stack, request, scope, op, id)
+	} else {
+		site.internalError(fmt.Errorf("panic %s in %s %s: %v\n%s", id, request.Method, request.URL.Path, recovered, stack))
+	}
+
+	err := site.HandleError(InternalError{Message: "An unexpected error occurred.", ID: id}, response, request, scope)
+	site.internalError(err)
+}
+
+// Returns a random id for a panic.
+func newPanicID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
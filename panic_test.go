@@ -0,0 +1,107 @@
+package rez
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ClickerMonkey/deps"
+	"github.com/ClickerMonkey/rez/api"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPanicHandler(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	site := New(router)
+
+	type panicked struct {
+		recovered any
+		stack     string
+		path      string
+		scope     *deps.Scope
+		op        *api.Operation
+		id        string
+	}
+	handled := []panicked{}
+	site.SetPanicHandler(func(recovered any, stack []byte, request *http.Request, scope *deps.Scope, op *api.Operation, id string) {
+		handled = append(handled, panicked{recovered, string(stack), request.URL.Path, scope, op, id})
+	})
+
+	site.Get("/boom", func() *testItem {
+		panic("kaboom")
+	})
+	site.Get("/traced", func() *testItem {
+		panic("traced")
+	})
+
+	res := httptest.NewRecorder()
+	site.Chi().ServeHTTP(res, httptest.NewRequest("GET", "/boom", nil))
+
+	assert.Equal(t, 500, res.Code)
+	if assert.Len(t, handled, 1) {
+		h := handled[0]
+		assert.Equal(t, "kaboom", h.recovered)
+		assert.Contains(t, h.stack, "panic_test.go")
+		assert.Equal(t, "/boom", h.path)
+		assert.NotNil(t, h.scope)
+		assert.Same(t, site.GetPath("/boom").Get, h.op)
+		assert.NotEmpty(t, h.id)
+
+		sent := InternalError{}
+		assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &sent))
+		assert.Equal(t, InternalError{Message: "An unexpected error occurred.", ID: h.id}, sent)
+		assert.NotContains(t, res.Body.String(), "kaboom")
+	}
+
+	res = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/traced", nil)
+	req.Header.Set(middleware.RequestIDHeader, "request-1")
+	site.Chi().ServeHTTP(res, req)
+
+	assert.Equal(t, 500, res.Code)
+	if assert.Len(t, handled, 2) {
+		assert.Equal(t, "request-1", handled[1].id)
+		assert.Equal(t, `{"message":"An unexpected error occurred.","id":"request-1"}`+"\n", res.Body.String())
+	}
+
+	doc := site.BuildDocument()
+	response := doc.Paths["/boom"].Get.Responses["500"]
+	if assert.NotNil(t, response) {
+		assert.Equal(t, "An unexpected error occurred.", response.Description)
+		assert.ElementsMatch(t, []string{"message", "id"}, keys(response.Content[api.ContentTypeJSON].Schema.Properties))
+	}
+}
+
+func TestPanicInternalError(t *testing.T) {
+	site := New(chi.NewRouter())
+	reported := []error{}
+	site.SetInternalErrorHandler(func(err error) {
+		reported = append(reported, err)
+	})
+	site.EnableProblemDetails(true)
+	site.Use(func(next MiddlewareNext) {
+		panic("in middleware")
+	})
+	site.Get("/boom", func() {})
+
+	res := httptest.NewRecorder()
+	site.Chi().ServeHTTP(res, httptest.NewRequest("GET", "/boom", nil))
+
+	assert.Equal(t, 500, res.Code)
+	assert.Equal(t, "application/problem+json", res.Header().Get("Content-Type"))
+	if assert.Len(t, reported, 1) {
+		message := reported[0].Error()
+		assert.True(t, strings.HasPrefix(message, "panic "), message)
+		assert.Contains(t, message, "in GET /boom: in middleware\n")
+		assert.Contains(t, message, "panic_test.go")
+
+		id := strings.Fields(message)[1]
+		assert.Len(t, id, 32)
+		assert.Equal(t, `{"title":"Internal Server Error","status":500,"detail":"An unexpected error occurred. (`+id+`)","instance":"/boom"}`+"\n", res.Body.String())
+	}
+}
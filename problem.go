@@ -0,0 +1,110 @@
+package rez
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+
+	"github.com/ClickerMonkey/rez/api"
+)
+
+// The name of the shared response which documents problem details.
+const ProblemResponseName = "Problem"
+
+// An error response in the problem details format of RFC 7807, sent as application/problem+json.
+// When problem details are enabled on a router every error is sent as a Problem.
+type Problem struct {
+	// A URI reference that identifies the problem type, about:blank when not given.
+	Type string `json:"type,omitempty" api:"format=uri-reference"`
+	// A short, human-readable summary of the problem type.
+	Title string `json:"title,omitempty"`
+	// The HTTP status code.
+	Status int `json:"status,omitempty"`
+	// A human-readable explanation specific to this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+	// A URI reference that identifies the specific occurrence of the problem.
+	Instance string `json:"instance,omitempty" api:"format=uri-reference"`
+	// The validation failures of the request, if any.
+	Errors []Validation `json:"errors,omitempty"`
+}
+
+var _ error = Problem{}
+var _ HasStatus = Problem{}
+var _ HasContentType = Problem{}
+
+// Converts the error into a problem. Validation errors become a 400 with their validations
+// as the errors, errors with a status have their message as the detail, and any other error
+// is a 500 without a detail so internal messages are not exposed.
+func NewProblem(err error, request *http.Request) Problem {
+	var problem Problem
+
+	var validator *Validator
+	var hasStatus HasStatus
+	switch {
+	case errors.As(err, &problem):
+	case errors.As(err, &validator):
+		problem.Status = validator.HTTPStatus()
+		problem.Detail = validator.Error()
+		problem.Errors = *validator.Validations
+	case errors.As(err, &hasStatus):
+		problem.Status = hasStatus.HTTPStatus()
+		problem.Detail = err.Error()
+	default:
+		problem.Status = http.StatusInternalServerError
+	}
+
+	if problem.Status == 0 {
+		problem.Status = http.StatusInternalServerError
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	if problem.Instance == "" && request != nil {
+		problem.Instance = request.URL.Path
+	}
+
+	return problem
+}
+
+// Problem implements error
+func (p Problem) Error() string {
+	if p.Detail != "" {
+		return p.Title + ": " + p.Detail
+	}
+	return p.Title
+}
+func (p Problem) HTTPStatus() int {
+	if p.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return p.Status
+}
+func (p Problem) HTTPStatuses() []int {
+	return []int{http.StatusInternalServerError}
+}
+func (p Problem) HTTPContentType() string {
+	return string(api.ContentTypeProblem)
+}
+
+var problemType = reflect.TypeOf(Problem{})
+
+// Sends errors in this router and sub routers created after this is set as a Problem
+// (RFC 7807). The Problem is added to the document as a shared response which is the
+// default response and the response of each error status of the operations.
+// An error handler set with SetErrorHandler is still given errors first.
+func (site *Site) EnableProblemDetails(enabled bool) {
+	site.problemDetails = enabled
+	if !enabled {
+		return
+	}
+
+	site.Open.AddResponse(ProblemResponseName, &api.Response{
+		Description: "A problem with the request as described by RFC 7807.",
+		Content: api.Contents{
+			api.ContentTypeProblem: &api.MediaType{
+				Schema: site.Open.GetSchema(problemType),
+			},
+		},
+	})
+	site.AddResponse("default", *site.Open.RefResponse(ProblemResponseName))
+}
@@ -0,0 +1,90 @@
+package rez
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ClickerMonkey/rez/api"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProblemDetails(t *testing.T) {
+	site := New(chi.NewRouter())
+	site.EnableValidation(true)
+
+	find := func(p Path[testItem]) (*testItem, *NotFound[string]) {
+		if p.Value.Name == "missing" {
+			return nil, NewNotFound("no item named missing")
+		}
+		return &p.Value, nil
+	}
+
+	site.Get("/items/{name}", find)
+	site.Route("/v2", func(r Router) {
+		r.EnableProblemDetails(true)
+		r.Get("/items/{name}", find)
+		r.Post("/tasks", func(b Body[testTask]) testTask {
+			return b.Value
+		})
+		r.Get("/fail", func() (*testItem, error) {
+			return nil, errors.New("connection refused")
+		})
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+		sent   string
+	}{
+		{"GET", "/items/missing", "", 404, `"no item named missing"`},
+		{"GET", "/v2/items/x", "", 200, `{"name":"x"}`},
+		{"GET", "/v2/items/missing", "", 404, `{"title":"Not Found","status":404,"detail":"no item named missing","instance":"/v2/items/missing"}`},
+		{"POST", "/v2/tasks", `{"name":"abcdefg","tags":[]}`, 400, `{"title":"Bad Request","status":400,"detail":"1 validation error","instance":"/v2/tasks","errors":[{"path":["body","name"],"rule":"maxLength","message":"7 exceeds the maximum length of 5"}]}`},
+		{"GET", "/v2/fail", "", 500, `{"title":"Internal Server Error","status":500,"instance":"/v2/fail"}`},
+	}
+
+	for _, test := range tests {
+		res := httptest.NewRecorder()
+		site.Chi().ServeHTTP(res, httptest.NewRequest(test.method, test.path, strings.NewReader(test.body)))
+
+		assert.Equal(t, test.status, res.Code, test.path)
+		assert.Equal(t, test.sent+"\n", res.Body.String(), test.path)
+		if test.status >= 400 && strings.HasPrefix(test.path, "/v2") {
+			assert.Equal(t, "application/problem+json", res.Header().Get("Content-Type"), test.path)
+		}
+	}
+
+	doc := site.BuildDocument()
+	problem := doc.Components.Responses[ProblemResponseName]
+	if assert.NotNil(t, problem) && assert.NotNil(t, problem.Content[api.ContentTypeProblem]) {
+		schema := problem.Content[api.ContentTypeProblem].Schema
+		assert.ElementsMatch(t, []string{"type", "title", "status", "detail", "instance", "errors"}, keys(schema.Properties))
+	}
+
+	ref := `{"$ref":"#/components/responses/Problem"}`
+	op := doc.Paths["/v2/items/{name}"].Get
+	assert.JSONEq(t, ref, toJSON(op.Responses["404"]))
+	assert.JSONEq(t, ref, toJSON(op.Responses["default"]))
+	assert.JSONEq(t, ref, toJSON(doc.Paths["/v2/fail"].Get.Responses["500"]))
+	assert.NotContains(t, doc.Paths["/items/{name}"].Get.Responses, "default")
+	assert.NotContains(t, toJSON(doc.Paths["/items/{name}"].Get.Responses["404"]), "Problem")
+}
+
+func keys[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for key := range m {
+		out = append(out, key)
+	}
+	return out
+}
+
+func toJSON(value any) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
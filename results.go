@@ -2,6 +2,7 @@ package rez
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"net/http"
 	"reflect"
@@ -13,6 +14,7 @@ import (
 type validResult interface {
 	json.Marshaler
 	json.Unmarshaler
+	xml.Marshaler
 	HasStatus
 	api.HasSchemaType
 	api.HasName
@@ -44,6 +46,9 @@ func (err OK[V]) HTTPStatuses() []int {
 func (err OK[V]) MarshalJSON() ([]byte, error) {
 	return json.Marshal(err.Result)
 }
+func (err OK[V]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(err.Result)
+}
 func (err *OK[V]) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &err.Result)
 }
@@ -74,6 +79,9 @@ func (err Created[V]) HTTPStatuses() []int {
 func (err Created[V]) MarshalJSON() ([]byte, error) {
 	return json.Marshal(err.Result)
 }
+func (err Created[V]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(err.Result)
+}
 func (err *Created[V]) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &err.Result)
 }
@@ -104,6 +112,9 @@ func (err Accepted[V]) HTTPStatuses() []int {
 func (err Accepted[V]) MarshalJSON() ([]byte, error) {
 	return json.Marshal(err.Result)
 }
+func (err Accepted[V]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(err.Result)
+}
 func (err *Accepted[V]) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &err.Result)
 }
@@ -134,6 +145,9 @@ func (err Moved[V]) HTTPStatuses() []int {
 func (err Moved[V]) MarshalJSON() ([]byte, error) {
 	return json.Marshal(err.Result)
 }
+func (err Moved[V]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(err.Result)
+}
 func (err *Moved[V]) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &err.Result)
 }
@@ -167,6 +181,9 @@ func (err BadRequest[V]) Error() string {
 func (err BadRequest[V]) MarshalJSON() ([]byte, error) {
 	return json.Marshal(err.Result)
 }
+func (err BadRequest[V]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(err.Result)
+}
 func (err *BadRequest[V]) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &err.Result)
 }
@@ -200,6 +217,9 @@ func (err Unauthorized[V]) Error() string {
 func (err Unauthorized[V]) MarshalJSON() ([]byte, error) {
 	return json.Marshal(err.Result)
 }
+func (err Unauthorized[V]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(err.Result)
+}
 func (err *Unauthorized[V]) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &err.Result)
 }
@@ -233,6 +253,9 @@ func (err PaymentRequired[V]) Error() string {
 func (err PaymentRequired[V]) MarshalJSON() ([]byte, error) {
 	return json.Marshal(err.Result)
 }
+func (err PaymentRequired[V]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(err.Result)
+}
 func (err *PaymentRequired[V]) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &err.Result)
 }
@@ -266,6 +289,9 @@ func (err Forbidden[V]) Error() string {
 func (err Forbidden[V]) MarshalJSON() ([]byte, error) {
 	return json.Marshal(err.Result)
 }
+func (err Forbidden[V]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(err.Result)
+}
 func (err *Forbidden[V]) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &err.Result)
 }
@@ -299,6 +325,9 @@ func (err NotFound[V]) Error() string {
 func (err NotFound[V]) MarshalJSON() ([]byte, error) {
 	return json.Marshal(err.Result)
 }
+func (err NotFound[V]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(err.Result)
+}
 func (err *NotFound[V]) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &err.Result)
 }
@@ -332,6 +361,9 @@ func (err Conflict[V]) Error() string {
 func (err Conflict[V]) MarshalJSON() ([]byte, error) {
 	return json.Marshal(err.Result)
 }
+func (err Conflict[V]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(err.Result)
+}
 func (err *Conflict[V]) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &err.Result)
 }
@@ -365,6 +397,9 @@ func (err TooManyRequests[V]) Error() string {
 func (err TooManyRequests[V]) MarshalJSON() ([]byte, error) {
 	return json.Marshal(err.Result)
 }
+func (err TooManyRequests[V]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(err.Result)
+}
 func (err *TooManyRequests[V]) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &err.Result)
 }
@@ -398,6 +433,9 @@ func (err InternalServerError[V]) Error() string {
 func (err InternalServerError[V]) MarshalJSON() ([]byte, error) {
 	return json.Marshal(err.Result)
 }
+func (err InternalServerError[V]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(err.Result)
+}
 func (err *InternalServerError[V]) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &err.Result)
 }
@@ -431,6 +469,9 @@ func (err NotImplemented[V]) Error() string {
 func (err NotImplemented[V]) MarshalJSON() ([]byte, error) {
 	return json.Marshal(err.Result)
 }
+func (err NotImplemented[V]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(err.Result)
+}
 func (err *NotImplemented[V]) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &err.Result)
 }
@@ -464,6 +505,9 @@ func (err ServiceUnavailable[V]) Error() string {
 func (err ServiceUnavailable[V]) MarshalJSON() ([]byte, error) {
 	return json.Marshal(err.Result)
 }
+func (err ServiceUnavailable[V]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(err.Result)
+}
 func (err *ServiceUnavailable[V]) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &err.Result)
 }
@@ -488,6 +532,7 @@ func NewResult[V any](status int, result V) *Result[V] {
 
 var _ json.Marshaler = &Result[string]{}
 var _ json.Unmarshaler = &Result[string]{}
+var _ xml.Marshaler = &Result[string]{}
 var _ HasStatus = &Result[string]{}
 var _ api.HasSchemaType = Result[string]{}
 
@@ -500,6 +545,9 @@ func (se Result[V]) HTTPStatuses() []int {
 func (se Result[V]) MarshalJSON() ([]byte, error) {
 	return json.Marshal(se.Value)
 }
+func (se Result[V]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(se.Value)
+}
 func (se *Result[V]) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &se.Value)
 }
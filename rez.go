@@ -35,11 +35,23 @@ type HasContentType interface {
 	HTTPContentType() string
 }
 
+// A response which can be sent as one of several content types. The content types
+// are documented on the response and the one sent is negotiated with the Accept header.
+type HasContentTypes interface {
+	HTTPContentTypes() []string
+}
+
 // A response which has custom sending logic.
 type CanSend interface {
 	HTTPSend(w http.ResponseWriter) error
 }
 
+// A response which is written to the client as it's produced instead of being
+// buffered. The request is given so the stream can end when the client goes away.
+type CanStream interface {
+	HTTPStream(w http.ResponseWriter, r *http.Request) error
+}
+
 // Router with OpenAPI integration and dependency injection
 type Router interface {
 	ValidationProvider
@@ -56,6 +68,14 @@ type Router interface {
 	// Sets the handler for errors we received outside of responding to the client.
 	SetInternalErrorHandler(handle InternalErrorHandler)
 
+	// Sets the handler which is given the panics of routes and middleware in this router and
+	// sub routers created after this is set. Without a handler panics are given to the
+	// InternalErrorHandler with their stack trace.
+	SetPanicHandler(handler PanicHandler)
+
+	// Sets the tracer given the spans of requests to this router and sub routers created after this is set.
+	SetTracer(tracer Tracer)
+
 	// Handles the given error if its a HandledError, is handled by the error handler, or is handled with default behavior.
 	HandleError(err error, response http.ResponseWriter, request *http.Request, scope *deps.Scope) error
 
@@ -66,6 +86,16 @@ type Router interface {
 	// Sets the validation options for the type or value's type.
 	SetValidationOptions(valueOrType any, options ValidationOptions)
 
+	// Sets how responses of routes in this router or sub routers created after this is set are
+	// validated against the schema documented for their status. By default responses are not validated.
+	SetResponseValidation(validation ResponseValidation)
+
+	// Sends errors in this router and sub routers created after this is set as a Problem
+	// (RFC 7807). The Problem is added to the document as a shared response which is the
+	// default response and the response of each error status of the operations.
+	// An error handler set with SetErrorHandler is still given errors first.
+	EnableProblemDetails(enabled bool)
+
 	// Sets the memory limit (in bytes) for multipart/form-data requests.
 	// Any request larger than this will utilize temporary files.
 	SetMemoryLimit(memoryLimit int64)
@@ -74,6 +104,38 @@ type Router interface {
 	// Any request larger than this will utilize temporary files.
 	GetMemoryLimit() int64
 
+	// Sets the maximum size (in bytes) of request bodies in this router and sub routers created
+	// after this is set, zero for no limit. Any request with a larger body is sent a
+	// 413 RequestEntityTooLarge, which is documented on the operations with a request body.
+	SetBodyLimit(bodyLimit int64)
+
+	// Gets the maximum size (in bytes) of request bodies, zero for no limit.
+	GetBodyLimit() int64
+
+	// Sets the options for decoding request bodies in this router and sub routers created
+	// after this is set. Bodies which break the options fail validation.
+	SetDecodeOptions(options DecodeOptions)
+
+	// Gets the options for decoding request bodies.
+	GetDecodeOptions() DecodeOptions
+
+	// Registers how to decode request bodies and encode responses of the given content type.
+	// The decoder or encoder can be nil if the content type is only accepted or only sent.
+	// Responses will be sent with this content type when the request's Accept header prefers it
+	// and the operations on this site will document it as a possible request and response content type.
+	// This affects the whole site, including routers created before this call.
+	RegisterCodec(contentType api.ContentType, decoder Decoder, encoder Encoder)
+
+	// Registers an encoder for the given content type. This is the same as RegisterCodec
+	// but keeps any decoder already registered for the content type.
+	RegisterEncoder(contentType api.ContentType, encoder Encoder)
+
+	// Returns the decoder for the given request content type, or nil if it's not supported.
+	GetDecoder(contentType api.ContentType) Decoder
+
+	// Returns the encoder for the given response content type, or nil if it's not supported.
+	GetEncoder(contentType api.ContentType) Encoder
+
 	// Adds the types of the given values as injectable request bodies. This avoids
 	// the necessity of rez.Body or rez.Request. If any of the values/types
 	// have already been defined this will cause a panic.
@@ -94,6 +156,11 @@ type Router interface {
 	// have already been defined this will cause a panic.
 	DefineHeader(headers ...any)
 
+	// Adds the types of the given values as injectable cookie values. This avoids
+	// the necessity of rez.Cookie. If any of the values/types
+	// have already been defined this will cause a panic.
+	DefineCookie(cookies ...any)
+
 	// Gets the base operation which has all inherited tags and responses set at the current router.
 	GetOperations() *api.Operation
 
@@ -189,7 +256,7 @@ type RouterOperation interface {
 	// The router of the operation
 	Router() Router
 
-	// Adds the given type/instance as an input (body, param, query, header) to the operation.
+	// Adds the given type/instance as an input (body, param, query, header, cookie) to the operation.
 	Input(input ...any)
 
 	// Adds the given type/instance as a response type to the operation.
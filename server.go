@@ -0,0 +1,205 @@
+package rez
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Options for the http.Server of a site.
+type ServerOptions struct {
+	// The TCP address to listen on, defaults to ":http" or ":https" with TLS.
+	Addr string
+	// The maximum duration for reading the entire request, including the body.
+	ReadTimeout time.Duration
+	// The amount of time allowed to read request headers, defaults to ReadTimeout.
+	ReadHeaderTimeout time.Duration
+	// The maximum duration before timing out writes of the response.
+	WriteTimeout time.Duration
+	// The maximum amount of time to wait for the next request when keep-alives are enabled,
+	// defaults to ReadTimeout.
+	IdleTimeout time.Duration
+	// The maximum number of bytes of the request headers, defaults to http.DefaultMaxHeaderBytes.
+	MaxHeaderBytes int
+	// The TLS certificate and matching key files. When given the server serves HTTPS.
+	CertFile string
+	KeyFile  string
+	// The TLS configuration, if the certificates are not loaded from files.
+	TLSConfig *tls.Config
+	// How long to wait for in-flight requests to finish when shutting down. Zero waits until they're done.
+	ShutdownTimeout time.Duration
+	// The signals which shut down the server gracefully when received by Serve.
+	// By default signals are not handled.
+	Signals []os.Signal
+}
+
+// The signals which stop a process in a terminal or a container, see ServerOptions.Signals.
+var ShutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// The servers created by a site, shared by a site and its sub routers.
+type siteServers struct {
+	mutex        sync.Mutex
+	list         []*http.Server
+	shuttingDown bool
+}
+
+func (s *siteServers) isShuttingDown() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.shuttingDown
+}
+
+// Removes the server so it isn't shut down with the site.
+func (s *siteServers) remove(server *http.Server) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, existing := range s.list {
+		if existing == server {
+			s.list = append(s.list[:i], s.list[i+1:]...)
+			return
+		}
+	}
+}
+
+// Returns an http.Server for the site with the given options. The server is shut down
+// with the site by Shutdown.
+func (site *Site) Server(options ServerOptions) *http.Server {
+	server := &http.Server{
+		Addr:              options.Addr,
+		Handler:           site.router,
+		TLSConfig:         options.TLSConfig,
+		ReadTimeout:       options.ReadTimeout,
+		ReadHeaderTimeout: options.ReadHeaderTimeout,
+		WriteTimeout:      options.WriteTimeout,
+		IdleTimeout:       options.IdleTimeout,
+		MaxHeaderBytes:    options.MaxHeaderBytes,
+	}
+
+	site.servers.mutex.Lock()
+	defer site.servers.mutex.Unlock()
+
+	site.servers.list = append(site.servers.list, server)
+
+	return server
+}
+
+// Serves the site with the given options until the context is done, one of the signals
+// in the options is received, or Shutdown is called. When the context is done or a signal
+// is received the site is shut down gracefully before returning. If the server failed or
+// didn't shut down in time the error is returned.
+func (site *Site) Serve(ctx context.Context, options ServerOptions) error {
+	server := site.Server(options)
+
+	if len(options.Signals) > 0 {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, options.Signals...)
+		defer stop()
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	stopped := make(chan error, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+			return
+		}
+		shutdownCtx := context.Background()
+		if options.ShutdownTimeout > 0 {
+			var cancel context.CancelFunc
+			shutdownCtx, cancel = context.WithTimeout(shutdownCtx, options.ShutdownTimeout)
+			defer cancel()
+		}
+		stopped <- site.Shutdown(shutdownCtx)
+	}()
+
+	var err error
+	if options.CertFile != "" || options.TLSConfig != nil {
+		err = server.ListenAndServeTLS(options.CertFile, options.KeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		site.servers.remove(server)
+		return err
+	}
+
+	// Closed by Shutdown, wait for it to finish when it was started by the context.
+	if ctx.Err() != nil {
+		return <-stopped
+	}
+	return nil
+}
+
+// Gracefully shuts down the servers of the site. The servers stop accepting requests and
+// in-flight requests are given until the context is done to finish, then the site's scope is
+// freed. The scopes of in-flight requests are freed when they finish. Every server is shut
+// down and the scope is freed even if a server didn't finish in time, the first error is returned.
+func (site *Site) Shutdown(ctx context.Context) error {
+	site.servers.mutex.Lock()
+	servers := site.servers.list
+	site.servers.list = nil
+	site.servers.shuttingDown = true
+	site.servers.mutex.Unlock()
+
+	var err error
+	for _, server := range servers {
+		if shutdownErr := server.Shutdown(ctx); shutdownErr != nil && err == nil {
+			err = shutdownErr
+		}
+	}
+	if freeErr := site.Scope.Free(); freeErr != nil && err == nil {
+		err = freeErr
+	}
+
+	return err
+}
+
+// Serves the site at the given address until it fails and returns the error.
+func (site *Site) Listen(addr string) error {
+	return site.Serve(context.Background(), ServerOptions{Addr: addr})
+}
+
+// Serves the site at the address in the --host argument (":80" by default) until it fails
+// or Shutdown is called. The arguments are read without the flag package so an application
+// can define its own flags. Signals are not handled, to shut down gracefully when the process
+// is interrupted or terminated use Serve with ServerOptions{Signals: ShutdownSignals}.
+func (site *Site) Run() error {
+	addr := hostArg(os.Args[1:])
+	if addr == "" {
+		addr = ":80"
+	}
+
+	return site.Serve(context.Background(), ServerOptions{Addr: addr})
+}
+
+// Returns the value of the -host or --host argument, if any.
+func hostArg(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if name == arg {
+			continue
+		}
+		if strings.HasPrefix(name, "host=") {
+			return strings.TrimPrefix(name, "host=")
+		}
+		if name == "host" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,183 @@
+package rez
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ClickerMonkey/deps"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type testConnection struct {
+	open bool
+}
+
+func TestShutdown(t *testing.T) {
+	site := New(chi.NewRouter())
+	site.Scope = deps.New()
+	deps.ProvideScoped(site.Scope, deps.Provider[testConnection]{
+		Create: func(scope *deps.Scope) (*testConnection, error) {
+			return &testConnection{open: true}, nil
+		},
+		Free: func(scope *deps.Scope, conn *testConnection) error {
+			conn.open = false
+			return nil
+		},
+	})
+	conn, _ := deps.GetScoped[testConnection](site.Scope)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	site.Get("/slow", func() string {
+		close(started)
+		<-release
+		return "done"
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	server := site.Server(ServerOptions{ReadTimeout: time.Second, IdleTimeout: time.Minute})
+	assert.Equal(t, time.Second, server.ReadTimeout)
+	assert.Equal(t, time.Minute, server.IdleTimeout)
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+
+	response := make(chan string, 1)
+	go func() {
+		res, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err != nil {
+			response <- err.Error()
+			return
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		response <- string(body)
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- site.Shutdown(context.Background())
+	}()
+
+	select {
+	case <-shutdown:
+		t.Fatal("shut down before the in-flight request finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.True(t, conn.open)
+
+	close(release)
+	assert.Equal(t, "\"done\"\n", <-response)
+	assert.NoError(t, <-shutdown)
+	assert.Equal(t, http.ErrServerClosed, <-served)
+	assert.False(t, conn.open)
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	site := New(chi.NewRouter())
+	site.Scope = deps.New()
+	deps.ProvideScoped(site.Scope, deps.Provider[testConnection]{
+		Create: func(scope *deps.Scope) (*testConnection, error) {
+			return &testConnection{open: true}, nil
+		},
+		Free: func(scope *deps.Scope, conn *testConnection) error {
+			conn.open = false
+			return nil
+		},
+	})
+	conn, _ := deps.GetScoped[testConnection](site.Scope)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	site.Get("/slow", func() string {
+		close(started)
+		<-release
+		return "done"
+	})
+
+	serve := func() (string, chan error) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		served := make(chan error, 1)
+		server := site.Server(ServerOptions{})
+		go func() {
+			served <- server.Serve(listener)
+		}()
+		return listener.Addr().String(), served
+	}
+	slowAddr, slowServed := serve()
+	_, otherServed := serve()
+
+	go func() {
+		res, err := http.Get("http://" + slowAddr + "/slow")
+		if err == nil {
+			res.Body.Close()
+		}
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, site.Shutdown(ctx), context.DeadlineExceeded)
+	for _, served := range []chan error{slowServed, otherServed} {
+		select {
+		case err := <-served:
+			assert.Equal(t, http.ErrServerClosed, err)
+		case <-time.After(time.Second):
+			t.Error("a server was not shut down")
+		}
+	}
+	assert.False(t, conn.open)
+}
+
+func TestServe(t *testing.T) {
+	site := New(chi.NewRouter())
+	site.Scope = deps.New()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	assert.NoError(t, site.Serve(ctx, ServerOptions{Addr: "127.0.0.1:0", ShutdownTimeout: time.Second}))
+
+	assert.Error(t, site.Serve(context.Background(), ServerOptions{Addr: "127.0.0.1:-1"}))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if assert.NoError(t, err) {
+		defer listener.Close()
+		assert.Error(t, site.Serve(context.Background(), ServerOptions{Addr: listener.Addr().String()}))
+	}
+	assert.Empty(t, site.servers.list)
+}
+
+func TestHostArg(t *testing.T) {
+	tests := []struct {
+		args []string
+		host string
+	}{
+		{[]string{}, ""},
+		{[]string{"-verbose", "--host", ":3000"}, ":3000"},
+		{[]string{"-host=:3000"}, ":3000"},
+		{[]string{"--host=localhost:80", "-x"}, "localhost:80"},
+		{[]string{"host", ":3000"}, ""},
+		{[]string{"--", "--host", ":3000"}, ""},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.host, hostArg(test.args), test.args)
+	}
+}
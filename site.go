@@ -3,10 +3,7 @@ package rez
 import (
 	"bytes"
 	"context"
-	"encoding"
 	"encoding/json"
-	"encoding/xml"
-	"flag"
 	"fmt"
 	"net/http"
 	"reflect"
@@ -14,6 +11,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ClickerMonkey/deps"
 	"github.com/ClickerMonkey/rez/api"
@@ -28,16 +26,26 @@ type Site struct {
 	ServeJSON bool
 	ServeXML  bool
 
-	injectTypes       map[reflect.Type]injectType
-	validationOptions map[reflect.Type]ValidationOptions
-	validationEnabled bool
-	errorHandler      ErrorHandler
-	internalHandler   InternalErrorHandler
-	router            chi.Router
-	url               string
-	baseOperation     api.Operation
-	openJsonPath      string
-	memoryLimit       int64
+	injectTypes        map[reflect.Type]injectType
+	validationOptions  map[reflect.Type]ValidationOptions
+	validationEnabled  bool
+	responseValidation ResponseValidation
+	problemDetails     bool
+	panicHandler       PanicHandler
+	tracer             Tracer
+	errorHandler       ErrorHandler
+	internalHandler    InternalErrorHandler
+	router             chi.Router
+	url                string
+	baseOperation      api.Operation
+	openJsonPath       string
+	memoryLimit        int64
+	bodyLimit          int64
+	decodeOptions      DecodeOptions
+	codecs             *codecs
+	tracker            *responseTracker
+	metrics            *siteMetrics
+	servers            *siteServers
 }
 
 var _ Router = &Site{}
@@ -56,6 +64,10 @@ func New(router chi.Router) *Site {
 		validationOptions: make(map[reflect.Type]ValidationOptions),
 		router:            router,
 		memoryLimit:       DEFAULT_MEMORY_LIMIT,
+		codecs:            &codecs{},
+		tracker:           &responseTracker{},
+		metrics:           &siteMetrics{},
+		servers:           &siteServers{},
 	}
 
 	site.Open.Document.OpenAPI = "3.0.0"
@@ -103,20 +115,11 @@ func (site *Site) HandleError(err error, response http.ResponseWriter, request *
 			return err
 		}
 	}
-
-	return site.Send(err, response)
-}
-
-// Handles a recovered panic
-func (site *Site) handlePanic(err any, response http.ResponseWriter, request *http.Request) {
-	var asErr error
-	if e, ok := err.(error); ok {
-		asErr = e
-	} else {
-		asErr = fmt.Errorf("unexpected error: %v", err)
+	if site.problemDetails {
+		return site.Respond(NewProblem(err, request), response, request)
 	}
-	asErr = site.HandleError(asErr, response, request, nil)
-	site.internalError(asErr)
+
+	return site.Respond(err, response, request)
 }
 
 // Returns the validation options specified for the given type.
@@ -139,6 +142,29 @@ func (site *Site) EnableValidation(enabled bool) {
 	site.validationEnabled = enabled
 }
 
+// Sets how responses of routes in this router or sub routers created after this is set are
+// validated against the schema documented for their status. By default responses are not validated.
+func (site *Site) SetResponseValidation(validation ResponseValidation) {
+	site.responseValidation = validation
+}
+
+// Validates the response if response validation is enabled. Invalid responses are given to
+// the InternalErrorHandler and in strict mode the error to send instead is returned.
+func (site *Site) validateResponse(op *api.Operation, response any, request *http.Request, scope *deps.Scope) error {
+	if site.responseValidation == ResponseValidationOff {
+		return nil
+	}
+	invalid := validateResponse(op, response, request, responseValidationProvider{site}, scope)
+	if invalid == nil {
+		return nil
+	}
+	site.internalError(*invalid)
+	if site.responseValidation == ResponseValidationStrict {
+		return NewInternalServerError(*invalid)
+	}
+	return nil
+}
+
 // Adds the values/types of the given injection type.
 func (site *Site) addInjectTypes(it injectType, valuesOrTypes []any) {
 	for _, valueOrType := range valuesOrTypes {
@@ -163,6 +189,28 @@ func (site *Site) GetMemoryLimit() int64 {
 	return site.memoryLimit
 }
 
+// Sets the maximum size (in bytes) of request bodies, zero for no limit.
+// Any request with a larger body is sent a 413 RequestEntityTooLarge.
+func (site *Site) SetBodyLimit(bodyLimit int64) {
+	site.bodyLimit = bodyLimit
+}
+
+// Gets the maximum size (in bytes) of request bodies, zero for no limit.
+// Any request with a larger body is sent a 413 RequestEntityTooLarge.
+func (site *Site) GetBodyLimit() int64 {
+	return site.bodyLimit
+}
+
+// Sets the options for decoding request bodies.
+func (site *Site) SetDecodeOptions(options DecodeOptions) {
+	site.decodeOptions = options
+}
+
+// Gets the options for decoding request bodies.
+func (site *Site) GetDecodeOptions() DecodeOptions {
+	return site.decodeOptions
+}
+
 // Adds the types of the given values as injectable request bodies. This avoids
 // the necessity of rez.Body or rez.Request. If any of the values/types
 // have already been defined this will cause a panic.
@@ -191,6 +239,13 @@ func (site *Site) DefineHeader(bodies ...any) {
 	site.addInjectTypes(injectTypeHeader, bodies)
 }
 
+// Adds the types of the given values as injectable cookie values. This avoids
+// the necessity of rez.Cookie. If any of the values/types
+// have already been defined this will cause a panic.
+func (site *Site) DefineCookie(cookies ...any) {
+	site.addInjectTypes(injectTypeCookie, cookies)
+}
+
 // Gets the base operation which has all inherited tags and responses set at the current router.
 func (site *Site) GetOperations() *api.Operation {
 	return &site.baseOperation
@@ -337,7 +392,7 @@ var injectableType = deps.TypeOf[Injectable]()
 func (site *Site) addInputType(op *api.Operation, inputType reflect.Type) bool {
 	concrete, ptr := getConcretePointer(inputType)
 
-	var bodyType, pathType, queryType, headerType reflect.Type
+	var bodyType, pathType, queryType, headerType, cookieType reflect.Type
 
 	if ptr.Implements(injectableType) {
 		argInstance := reflect.New(concrete).Interface()
@@ -347,6 +402,7 @@ func (site *Site) addInputType(op *api.Operation, inputType reflect.Type) bool {
 			pathType = requestTypes.Path
 			queryType = requestTypes.Query
 			headerType = requestTypes.Header
+			cookieType = requestTypes.Cookie
 		}
 	}
 
@@ -360,6 +416,8 @@ func (site *Site) addInputType(op *api.Operation, inputType reflect.Type) bool {
 			pathType = concrete
 		case injectTypeHeader:
 			headerType = concrete
+		case injectTypeCookie:
+			cookieType = concrete
 		}
 	}
 
@@ -373,11 +431,10 @@ func (site *Site) addInputType(op *api.Operation, inputType reflect.Type) bool {
 			}
 			op.RequestBody.Required = true
 			op.RequestBody.Description = api.GetDescription(bodyType)
-			if site.ServeJSON {
-				if op.RequestBody.Content == nil {
-					op.RequestBody.Content = api.Contents{}
-				}
-				contentType := bodySchema.ContentType()
+			if op.RequestBody.Content == nil {
+				op.RequestBody.Content = api.Contents{}
+			}
+			for _, contentType := range site.requestContentTypes(bodySchema) {
 				op.RequestBody.Content[contentType] = &api.MediaType{
 					Schema:  bodySchema,
 					Example: api.GetExample(bodyType),
@@ -403,6 +460,10 @@ func (site *Site) addInputType(op *api.Operation, inputType reflect.Type) bool {
 		op.AddParameters(site.Open, api.ParameterInHeader, headerType)
 		handled = true
 	}
+	if cookieType != nil {
+		op.AddParameters(site.Open, api.ParameterInCookie, cookieType)
+		handled = true
+	}
 
 	return handled
 }
@@ -432,6 +493,19 @@ func (site *Site) addOutputType(op *api.Operation, responseType reflect.Type) bo
 	if len(statuses) == 0 {
 		statuses = []int{200}
 	}
+	if site.problemDetails && out.Implements(errorType) {
+		for _, status := range statuses {
+			key := strconv.Itoa(status)
+			if op.Responses == nil {
+				op.Responses = api.Responses{}
+			}
+			if op.Responses[key] == nil {
+				op.Responses[key] = site.Open.RefResponse(ProblemResponseName)
+			}
+		}
+		return true
+	}
+	contentTypes := site.responseContentTypes(out)
 	for _, status := range statuses {
 		key := strconv.Itoa(status)
 		if op.Responses == nil {
@@ -442,22 +516,31 @@ func (site *Site) addOutputType(op *api.Operation, responseType reflect.Type) bo
 			existing = &api.Response{}
 			op.Responses[key] = existing
 		}
-		if existing.Content == nil {
-			existing.Content = api.Contents{}
-		}
 		if existing.Description == "" {
 			existing.Description = api.GetDescription(out)
 		}
-		content := existing.Content[api.ContentTypeJSON]
-		if content == nil {
-			content = &api.MediaType{}
-			existing.Content[api.ContentTypeJSON] = content
+		// A 204 has no body.
+		if status == http.StatusNoContent {
+			if existing.Description == "" {
+				existing.Description = http.StatusText(status)
+			}
+			continue
 		}
-		if content.Schema != nil {
-			merged := content.Schema.Merge(*outSchema)
-			content.Schema = &merged
-		} else {
-			content.Schema = outSchema
+		if existing.Content == nil {
+			existing.Content = api.Contents{}
+		}
+		for _, contentType := range contentTypes {
+			content := existing.Content[contentType]
+			if content == nil {
+				content = &api.MediaType{}
+				existing.Content[contentType] = content
+			}
+			if content.Schema != nil {
+				merged := content.Schema.Merge(*outSchema)
+				content.Schema = &merged
+			} else {
+				content.Schema = outSchema
+			}
 		}
 	}
 
@@ -473,25 +556,48 @@ func (site *Site) handle(fn any, op *api.Operation) http.HandlerFunc {
 
 	if op != nil {
 		*op = op.Merge(site.getOperation(fn))
+		if op.Responses["406"] == nil && isNegotiated(op) {
+			site.addOutputType(op, notAcceptableType)
+		}
+		if op.Responses["500"] == nil {
+			site.addOutputType(op, internalErrorType)
+		}
+		if site.bodyLimit > 0 && op.RequestBody != nil && op.Responses["413"] == nil {
+			site.addOutputType(op, requestEntityTooLargeType)
+		}
 	}
 
-	return func(w http.ResponseWriter, request *http.Request) {
+	name := funcName(fn)
+
+	serve := func(w http.ResponseWriter, request *http.Request) {
+		w, request, endTrace := site.traceRequest(w, request)
+		defer endTrace()
+
+		var scope *deps.Scope
 		defer func() {
-			if err := recover(); err != nil {
-				site.handlePanic(err, w, request)
+			if recovered := recover(); recovered != nil {
+				site.handlePanic(recovered, w, request, scope, op)
 			}
 		}()
 
 		scope, freeScope := site.GetScope(w, request)
+		site.setScopeRouter(scope)
 
 		scope.Set(op)
+		traceOperation(scope, op)
 
+		span := startSpan(scope, SpanKindHandler, name)
 		result, err := scope.Invoke(fn)
 		if err == nil {
 			err = result.Err()
 		}
+		span.end(err)
 
+		span = startSpan(scope, SpanKindSend, "send")
 		if err != nil {
+			if invalid := site.validateResponse(op, err, request, scope); invalid != nil {
+				err = invalid
+			}
 			err := site.HandleError(err, w, request, scope)
 			site.internalError(err)
 		} else {
@@ -500,20 +606,84 @@ func (site *Site) handle(fn any, op *api.Operation) http.HandlerFunc {
 			if len(returned) > 0 {
 				response = returned[0]
 			}
+			if invalid := site.validateResponse(op, response, request, scope); invalid != nil {
+				response = invalid
+			}
 
-			err := site.Send(response, w)
+			err := site.Respond(response, w, request)
 			site.internalError(err)
 		}
+		span.end(nil)
 
 		if freeScope {
 			err = scope.Free()
 			site.internalError(err)
 		}
 	}
+
+	if op == nil {
+		return serve
+	}
+
+	return func(w http.ResponseWriter, request *http.Request) {
+		tracked := site.tracker.enabled()
+		measuring := site.metrics.enabled()
+		if !tracked && !measuring {
+			serve(w, request)
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		if measuring {
+			start := time.Now()
+			key := site.metrics.start(op, request)
+			defer func() {
+				site.metrics.finish(key, recorder, time.Since(start))
+			}()
+		}
+
+		serve(recorder, request)
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		if !tracked {
+			return
+		}
+
+		undocumented, tracking := site.tracker.record(op, recorder, request)
+		if undocumented != nil {
+			switch tracking {
+			case ResponseTrackingReport:
+				site.internalError(*undocumented)
+			case ResponseTrackingPanic:
+				panic(*undocumented)
+			}
+		}
+	}
 }
 
-// Sends the response to the writer.
+// Sends the response to the writer. Without a request to negotiate with the response
+// is sent with its first content type, JSON unless the site doesn't serve it.
 func (site *Site) Send(response any, w http.ResponseWriter) error {
+	return site.Respond(response, w, nil)
+}
+
+// Sends the response to the writer for the request. The content type is negotiated
+// with the request's Accept header unless the response has a specific content type.
+// If no content type is acceptable to the client a NotAcceptable error is sent.
+func (site *Site) Respond(response any, w http.ResponseWriter, request *http.Request) error {
+	if canStream, ok := response.(CanStream); ok {
+		if hasContentTypes, ok := response.(HasContentTypes); ok && request != nil {
+			accept := request.Header.Get("Accept")
+			offers := toContentTypes(hasContentTypes.HTTPContentTypes())
+			if len(offers) > 0 {
+				if _, acceptable := negotiate(accept, offers); !acceptable {
+					return site.HandleError(NotAcceptable{Accept: accept, Available: fromContentTypes(offers)}, w, request, nil)
+				}
+			}
+		}
+		return canStream.HTTPStream(w, request)
+	}
 	if canSend, ok := response.(CanSend); ok {
 		return canSend.HTTPSend(w)
 	}
@@ -532,48 +702,48 @@ func (site *Site) Send(response any, w http.ResponseWriter) error {
 		return err
 	}
 
-	contentType := string(api.ContentTypeJSON)
+	var offers []api.ContentType
 	if hasContentType, ok := response.(HasContentType); ok {
-		contentType = hasContentType.HTTPContentType()
+		offers = []api.ContentType{api.ContentType(hasContentType.HTTPContentType())}
+	} else {
+		offers = site.responseContentTypes(reflect.TypeOf(response))
+	}
+
+	accept := ""
+	if request != nil {
+		accept = request.Header.Get("Accept")
+	}
+	contentType, acceptable := negotiate(accept, offers)
+	if !acceptable {
+		if status < http.StatusBadRequest {
+			return site.HandleError(NotAcceptable{Accept: accept, Available: fromContentTypes(offers)}, w, request, nil)
+		}
+		// Errors are still sent even if the client won't accept them.
+		contentType = offers[0]
+	}
+	if len(offers) > 1 {
+		w.Header().Add("Vary", "Accept")
 	}
 
 	w.Header().Set("Content-Type", string(contentType))
-	w.WriteHeader(status)
 
-	var err error
 	data := &bytes.Buffer{}
-
-	switch {
-	case strings.Contains(contentType, "xml"):
-		enc := xml.NewEncoder(data)
-		err = enc.Encode(response)
-	case strings.Contains(contentType, "json"):
-		enc := json.NewEncoder(data)
-		err = enc.Encode(response)
-	case strings.Contains(contentType, "text"):
-		if marshaller, ok := response.(encoding.TextMarshaler); ok {
-			text, err := marshaller.MarshalText()
-			if err != nil {
-				return err
-			}
-			data.Write(text)
-		} else if bytes, ok := response.([]byte); ok {
-			data.Write(bytes)
-		} else {
-			data.WriteString(toString(response))
+	if encoder := site.GetEncoder(contentType); encoder != nil {
+		err := encoder(data, response)
+		if err != nil {
+			return err
 		}
 	}
 
-	if err != nil {
-		return err
-	}
-
+	var err error
 	if data.Len() > 0 {
 		w.Header().Set("Content-Length", strconv.Itoa(data.Len()))
+		w.WriteHeader(status)
 
 		_, err = w.Write(data.Bytes())
 	} else {
 		// Unsupported type. Implement HTTPSend for this response type.
+		w.WriteHeader(status)
 		_, err = w.Write(nil)
 	}
 
@@ -610,6 +780,14 @@ func (site *Site) GetScope(response http.ResponseWriter, request *http.Request)
 	return
 }
 
+// Sets the router of the scope to this site. The scope is created by the first router the
+// request reaches, so this makes the settings of the router where the middleware was used
+// or the route was defined on (like the body limit) apply to what it injects.
+func (site *Site) setScopeRouter(scope *deps.Scope) {
+	router := Router(site)
+	deps.SetScoped(scope, &router)
+}
+
 // Given a type and scope, try to return an injection value.
 func (site *Site) dynamicRequestInjection(typ reflect.Type, scope *deps.Scope) (any, error) {
 	typ = getConcrete(typ)
@@ -627,6 +805,8 @@ func (site *Site) dynamicRequestInjection(typ reflect.Type, scope *deps.Scope) (
 			inj = &Query[any]{Value: val}
 		case injectTypeHeader:
 			inj = &Header[any]{Value: val}
+		case injectTypeCookie:
+			inj = &Cookie[any]{Value: val}
 		}
 
 		err := inj.ProvideDynamic(scope)
@@ -643,6 +823,8 @@ func (site *Site) dynamicRequestInjection(typ reflect.Type, scope *deps.Scope) (
 			val = v.Value
 		case *Header[any]:
 			val = v.Value
+		case *Cookie[any]:
+			val = v.Value
 		}
 
 		return val, nil
@@ -674,22 +856,31 @@ func NewMiddlewareNext(h http.Handler, scope *deps.Scope) MiddlewareNext {
 // then the next handler in the stack will not be invoked and the error
 // will be handled like any other error.
 func (site *Site) middleware(fn any) func(h http.Handler) http.Handler {
+	name := funcName(fn)
+
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+			w, request, endTrace := site.traceRequest(w, request)
+			defer endTrace()
+
+			var scope *deps.Scope
 			defer func() {
-				if err := recover(); err != nil {
-					site.handlePanic(err, w, request)
+				if recovered := recover(); recovered != nil {
+					site.handlePanic(recovered, w, request, scope, nil)
 				}
 			}()
 
 			scope, freeScope := site.GetScope(w, request)
+			site.setScopeRouter(scope)
 
 			scope.Set(NewMiddlewareNext(h, scope))
 
+			span := startSpan(scope, SpanKindMiddleware, name)
 			result, err := scope.Invoke(fn)
 			if err == nil {
 				err = result.Err()
 			}
+			span.end(err)
 
 			if err != nil {
 				site.HandleError(err, w, request, scope)
@@ -845,6 +1036,27 @@ func (site *Site) ServeOpenJSON(pattern string) {
 	})
 }
 
+// Writes the document to the file at path, see api.Builder.WriteSpec.
+func (site *Site) WriteSpec(path string) error {
+	return site.Open.WriteSpec(path)
+}
+
+func (site *Site) BuildYAML() []byte {
+	return site.Open.BuildYAML()
+}
+
+// Serves the document as YAML at the given pattern, built on the first request.
+func (site *Site) ServeOpenYAML(pattern string) {
+	var builtYaml []byte = nil
+
+	site.router.Get(site.url+pattern, func(w http.ResponseWriter, request *http.Request) {
+		if builtYaml == nil {
+			builtYaml = site.BuildYAML()
+		}
+		site.SendAny(builtYaml, w, 200, api.ContentTypeYAML)
+	})
+}
+
 func (site *Site) ensureServeOpenJSON(pattern string) {
 	if site.openJsonPath == "" {
 		replaceEnd := regexp.MustCompile(`[^/]+$`)
@@ -857,33 +1069,8 @@ func (site *Site) ensureServeOpenJSON(pattern string) {
 func (site *Site) ServeSwaggerUI(pattern string, options map[string]any) {
 	site.ensureServeOpenJSON(pattern)
 
-	if options == nil {
-		options = make(map[string]any)
-	}
-	options["url"] = site.openJsonPath
-	options["dom_id"] = "#ui-wrapper-new"
-	if _, exists := options["docExpansion"]; !exists {
-		options["docExpansion"] = "list"
-	}
-	if _, exists := options["deepLinking"]; !exists {
-		options["deepLinking"] = true
-	}
-	if _, exists := options["filter"]; !exists {
-		options["filter"] = true
-	}
-	optionsJson, _ := json.Marshal(options)
-
-	title := "Docs"
-	description := "Rest API Documentation"
-
-	if site.Open != nil {
-		if site.Open.Document.Info.Title != "" {
-			title = site.Open.Document.Info.Title
-		}
-		if site.Open.Document.Info.Description != "" {
-			description = site.Open.Document.Info.Description
-		}
-	}
+	optionsJson := site.swaggerUIOptions(options)
+	title, description := site.docsInfo()
 
 	html := []byte(`
 <!DOCTYPE html>
@@ -933,18 +1120,7 @@ func (site *Site) ServeRedoc(pattern string, options map[string]any) {
 		options = make(map[string]any)
 	}
 	optionsJson, _ := json.Marshal(options)
-
-	title := "Docs"
-	description := "Rest API Documentation"
-
-	if site.Open != nil {
-		if site.Open.Document.Info.Title != "" {
-			title = site.Open.Document.Info.Title
-		}
-		if site.Open.Document.Info.Description != "" {
-			description = site.Open.Document.Info.Description
-		}
-	}
+	title, description := site.docsInfo()
 
 	html := []byte(`
 <!DOCTYPE html>
@@ -975,19 +1151,6 @@ func (site *Site) ServeRedoc(pattern string, options map[string]any) {
 	})
 }
 
-func (site *Site) Listen(addr string) {
-	http.ListenAndServe(addr, site.router)
-}
-
-func (site *Site) Run() {
-	addr := ":80"
-
-	flag.StringVar(&addr, "host", ":80", "The port & host to serve requests from.")
-	flag.Parse()
-
-	site.Listen(addr)
-}
-
 func (site *Site) PrintPaths() {
 	doc := site.BuildDocument()
 
@@ -1156,6 +1319,7 @@ const (
 	injectTypePath
 	injectTypeQuery
 	injectTypeHeader
+	injectTypeCookie
 )
 
 func reflectType(x any) reflect.Type {
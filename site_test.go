@@ -0,0 +1,250 @@
+package rez
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ClickerMonkey/rez/api"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type testSession struct {
+	Session string `json:"session"`
+	Visits  int    `json:"visits"`
+}
+
+func TestCookie(t *testing.T) {
+	site := New(chi.NewRouter())
+	site.DefineCookie(testSession{})
+
+	site.Get("/wrapped", func(c Cookie[testSession]) string {
+		return c.Value.Session
+	})
+	site.Get("/defined", func(s testSession) int {
+		return s.Visits
+	})
+
+	req := httptest.NewRequest("GET", "/wrapped", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	res := httptest.NewRecorder()
+	site.Chi().ServeHTTP(res, req)
+
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "\"abc\"\n", res.Body.String())
+
+	req = httptest.NewRequest("GET", "/defined", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	req.AddCookie(&http.Cookie{Name: "visits", Value: "3"})
+	res = httptest.NewRecorder()
+	site.Chi().ServeHTTP(res, req)
+
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "3\n", res.Body.String())
+
+	doc := site.BuildDocument()
+	expected := map[string]bool{"session": true, "visits": true}
+	assert.Equal(t, expected, paramNames(doc.Paths["/wrapped"].Get, api.ParameterInCookie))
+	assert.Equal(t, expected, paramNames(doc.Paths["/defined"].Get, api.ParameterInCookie))
+}
+
+func paramNames(op *api.Operation, in api.ParameterIn) map[string]bool {
+	names := map[string]bool{}
+	for _, param := range op.GetParameters(in) {
+		names[param.Name] = param.Required
+	}
+	return names
+}
+
+type testItem struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func TestNegotiation(t *testing.T) {
+	site := New(chi.NewRouter())
+	site.ServeXML = true
+	site.RegisterEncoder("text/csv", func(w io.Writer, value any) error {
+		_, err := io.WriteString(w, "name\n"+value.(*OK[testItem]).Result.Name+"\n")
+		return err
+	})
+
+	site.Get("/item", func() *OK[testItem] {
+		return NewOK(testItem{Name: "x"})
+	})
+
+	tests := []struct {
+		accept      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"", 200, "application/json", "{\"name\":\"x\"}\n"},
+		{"*/*", 200, "application/json", "{\"name\":\"x\"}\n"},
+		{"application/xml", 200, "application/xml", "<testItem><name>x</name></testItem>"},
+		{"application/json;q=0.5, application/*", 200, "application/xml", "<testItem><name>x</name></testItem>"},
+		{"text/csv, application/json;q=0.9", 200, "text/csv", "name\nx\n"},
+		{"image/png", 406, "application/json", "{\"accept\":\"image/png\",\"available\":[\"application/json\",\"application/xml\",\"text/csv\"]}\n"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/item", nil)
+		req.Header.Set("Accept", test.accept)
+		res := httptest.NewRecorder()
+		site.Chi().ServeHTTP(res, req)
+
+		assert.Equal(t, test.status, res.Code, test.accept)
+		assert.Equal(t, test.contentType, res.Header().Get("Content-Type"), test.accept)
+		assert.Equal(t, test.body, res.Body.String(), test.accept)
+	}
+
+	doc := site.BuildDocument()
+	content := doc.Paths["/item"].Get.Responses["200"].Content
+	assert.Len(t, content, 3)
+	assert.NotNil(t, content[api.ContentTypeJSON])
+	assert.NotNil(t, content[api.ContentTypeXML])
+	assert.NotNil(t, content["text/csv"])
+
+	notAcceptable := doc.Paths["/item"].Get.Responses["406"]
+	if assert.NotNil(t, notAcceptable) {
+		assert.Equal(t, "None of the content types of the response are acceptable.", notAcceptable.Description)
+		assert.NotNil(t, notAcceptable.Content[api.ContentTypeJSON])
+	}
+
+	// Send has no request to negotiate with, Respond negotiates with the request.
+	res := httptest.NewRecorder()
+	assert.NoError(t, site.Send(NewOK(testItem{Name: "y"}), res))
+	assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"name\":\"y\"}\n", res.Body.String())
+
+	res = httptest.NewRecorder()
+	assert.NoError(t, site.Send(NewStreamSlice([]testItem{{Name: "y"}}), res))
+	assert.Equal(t, "[{\"name\":\"y\"}]\n", res.Body.String())
+
+	req := httptest.NewRequest("GET", "/item", nil)
+	req.Header.Set("Accept", "application/xml")
+	res = httptest.NewRecorder()
+	assert.NoError(t, site.Respond(NewOK(testItem{Name: "y"}), res, req))
+	assert.Equal(t, "application/xml", res.Header().Get("Content-Type"))
+	assert.Equal(t, "<testItem><name>y</name></testItem>", res.Body.String())
+}
+
+func TestCodecs(t *testing.T) {
+	site := New(chi.NewRouter())
+	site.RegisterCodec("text/csv", func(r io.Reader, target any) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		target.(*testItem).Name = lines[len(lines)-1]
+		return nil
+	}, nil)
+
+	site.Post("/item", func(b Body[testItem]) string {
+		return b.Value.Name
+	})
+
+	req := httptest.NewRequest("POST", "/item", strings.NewReader("name\ny\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	res := httptest.NewRecorder()
+	site.Chi().ServeHTTP(res, req)
+
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "\"y\"\n", res.Body.String())
+
+	req = httptest.NewRequest("POST", "/item", strings.NewReader("name: y"))
+	req.Header.Set("Content-Type", "application/yaml")
+	res = httptest.NewRecorder()
+	site.Chi().ServeHTTP(res, req)
+
+	assert.Equal(t, 415, res.Code)
+
+	doc := site.BuildDocument()
+	op := doc.Paths["/item"].Post
+	assert.Len(t, op.RequestBody.Content, 2)
+	assert.NotNil(t, op.RequestBody.Content["text/csv"])
+	assert.Len(t, op.Responses["200"].Content, 2)
+	assert.Nil(t, op.Responses["200"].Content["text/csv"])
+}
+
+func TestServeOpenYAML(t *testing.T) {
+	site := New(chi.NewRouter())
+	site.Get("/item", func() *testItem {
+		return nil
+	})
+	site.ServeOpenYAML("/openapi3.yaml")
+
+	res := httptest.NewRecorder()
+	site.Chi().ServeHTTP(res, httptest.NewRequest("GET", "/openapi3.yaml", nil))
+
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, string(api.ContentTypeYAML), res.Header().Get("Content-Type"))
+	assert.Equal(t, string(site.BuildYAML()), res.Body.String())
+
+	doc, err := api.ParseDocument(res.Body.Bytes())
+	if assert.NoError(t, err) {
+		assert.NotNil(t, doc.Paths["/item"].Get)
+	}
+}
+
+type testTask struct {
+	Name string   `json:"name" api:"maxLength=5"`
+	Tags []string `json:"tags"`
+}
+
+func TestResponseValidation(t *testing.T) {
+	site := New(chi.NewRouter())
+	reported := []error{}
+	site.SetInternalErrorHandler(func(err error) {
+		reported = append(reported, err)
+	})
+	site.SetResponseValidation(ResponseValidationReport)
+
+	site.Get("/valid", func() testTask {
+		return testTask{Name: "a", Tags: []string{"x"}}
+	})
+	site.Get("/invalid", func() testTask {
+		return testTask{Name: "abcdefg"}
+	})
+	site.Get("/missing", func() (*testTask, *NotFound[testItem]) {
+		return nil, NewNotFound(testItem{Name: "task"})
+	})
+	site.Route("/strict", func(r Router) {
+		r.SetResponseValidation(ResponseValidationStrict)
+		r.Get("/invalid", func() *OK[[]testTask] {
+			return NewOK([]testTask(nil))
+		})
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		site.Chi().ServeHTTP(res, httptest.NewRequest("GET", path, nil))
+		return res
+	}
+
+	res := serve("/valid")
+	assert.Equal(t, 200, res.Code)
+	assert.Empty(t, reported)
+
+	res = serve("/missing")
+	assert.Equal(t, 404, res.Code)
+	assert.Empty(t, reported)
+
+	res = serve("/invalid")
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "{\"name\":\"abcdefg\",\"tags\":null}\n", res.Body.String())
+	if assert.Len(t, reported, 1) {
+		assert.Equal(t, "GET /invalid responded 200 which does not match its documentation: name: 7 exceeds the maximum length of 5, response: tags is a required field", reported[0].Error())
+	}
+
+	reported = reported[:0]
+	res = serve("/strict/invalid")
+	assert.Equal(t, 500, res.Code)
+	assert.Equal(t, "{\"method\":\"GET\",\"path\":\"/strict/invalid\",\"status\":200,\"validations\":[{\"rule\":\"nullable\"}]}\n", res.Body.String())
+	if assert.Len(t, reported, 1) {
+		assert.IsType(t, InvalidResponse{}, reported[0])
+	}
+}
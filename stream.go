@@ -0,0 +1,355 @@
+package rez
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ClickerMonkey/rez/api"
+)
+
+// An event which has a name. The name is sent as the `event` field of a server-sent event.
+type HasEventName interface {
+	HTTPEventName() string
+}
+
+// An event which has an id. The id is sent as the `id` field of a server-sent event.
+type HasEventID interface {
+	HTTPEventID() string
+}
+
+// A server-sent events (text/event-stream) response. Each event is encoded as JSON
+// into the data of the event and flushed to the client as soon as it's received. The
+// stream ends when the events are done or when the client closes the request. The
+// event type E is documented as the response schema. A stream without events or
+// a function is sent as a 204.
+type EventStream[E any] struct {
+	// The events to send, the stream ends when the channel is closed.
+	Events <-chan E
+	// The events to send, the stream ends when the function returns.
+	// This has the same signature as iter.Seq[E]. If the function returns
+	// false the client has closed the request and no more events can be sent.
+	Seq func(yield func(E) bool)
+	// The reconnection time the client should use if the connection is lost.
+	Retry time.Duration
+	// If non-zero a comment is sent on this interval when there are no events
+	// to keep intermediaries from closing an idle connection. Only used with Events.
+	KeepAlive time.Duration
+}
+
+// Creates an event stream which sends the events from the channel until it's closed.
+func NewEventStream[E any](events <-chan E) *EventStream[E] {
+	return &EventStream[E]{Events: events}
+}
+
+// Creates an event stream which sends the events yielded by the function until it returns.
+func NewEventStreamFunc[E any](seq func(yield func(E) bool)) *EventStream[E] {
+	return &EventStream[E]{Seq: seq}
+}
+
+var _ CanStream = &EventStream[string]{}
+var _ HasStatus = EventStream[string]{}
+var _ HasContentType = EventStream[string]{}
+var _ api.HasSchemaType = EventStream[string]{}
+var _ api.HasName = EventStream[string]{}
+
+func (es EventStream[E]) HTTPStatus() int {
+	return http.StatusOK
+}
+func (es EventStream[E]) HTTPStatuses() []int {
+	return []int{http.StatusOK, http.StatusNoContent}
+}
+func (es EventStream[E]) HTTPContentType() string {
+	return string(api.ContentTypeEventStream)
+}
+func (es EventStream[E]) APISchemaType() any {
+	var event E
+	return event
+}
+func (es EventStream[E]) APIName() string {
+	var event E
+	return getResultAPIName(event, "EventStream")
+}
+func (es *EventStream[E]) HTTPStream(w http.ResponseWriter, r *http.Request) error {
+	if es == nil || (es.Events == nil && es.Seq == nil) {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", string(api.ContentTypeEventStream))
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if es.Retry > 0 {
+		if _, err := w.Write([]byte("retry: " + strconv.FormatInt(es.Retry.Milliseconds(), 10) + "\n\n")); err != nil {
+			return err
+		}
+	}
+	flush()
+
+	ctx := requestContext(r)
+	var err error
+
+	send := func(event E) bool {
+		data, encodeErr := encodeEvent(event)
+		if encodeErr != nil {
+			err = encodeErr
+			return false
+		}
+		if _, writeErr := w.Write(data); writeErr != nil {
+			err = writeErr
+			return false
+		}
+		flush()
+		return true
+	}
+
+	if es.Seq != nil {
+		es.Seq(func(event E) bool {
+			if ctx.Err() != nil {
+				return false
+			}
+			return send(event)
+		})
+		return err
+	}
+
+	var keepAlive <-chan time.Time
+	if es.KeepAlive > 0 {
+		ticker := time.NewTicker(es.KeepAlive)
+		defer ticker.Stop()
+		keepAlive = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-keepAlive:
+			if _, err := w.Write([]byte(":\n\n")); err != nil {
+				return err
+			}
+			flush()
+		case event, ok := <-es.Events:
+			if !ok || !send(event) {
+				return err
+			}
+		}
+	}
+}
+
+// Encodes the event in the text/event-stream format.
+func encodeEvent(event any) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	out := bytes.Buffer{}
+	if named, ok := event.(HasEventName); ok {
+		if name := named.HTTPEventName(); name != "" {
+			out.WriteString("event: " + singleLine(name) + "\n")
+		}
+	}
+	if identified, ok := event.(HasEventID); ok {
+		if id := identified.HTTPEventID(); id != "" {
+			out.WriteString("id: " + singleLine(id) + "\n")
+		}
+	}
+	out.WriteString("data: ")
+	out.Write(data)
+	out.WriteString("\n\n")
+
+	return out.Bytes(), nil
+}
+
+// Removes line breaks which would end an event field early.
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// A streamed list response. Items are encoded as JSON and written to the client
+// as they're received, either as a JSON array or as newline delimited JSON. The
+// stream ends when the items are done or when the client closes the request. The
+// response is documented as an array of T.
+type Stream[T any] struct {
+	// The items to send, the stream ends when the channel is closed.
+	Items <-chan T
+	// The items to send, the stream ends when the function returns.
+	// This has the same signature as iter.Seq[T]. If the function returns
+	// false the client has closed the request and no more items can be sent.
+	Seq func(yield func(T) bool)
+	// The content type to send, api.ContentTypeJSON or api.ContentTypeNDJSON.
+	// If not specified it's negotiated with the request's Accept header.
+	ContentType api.ContentType
+	// How many items are written between flushes to the client. Defaults to 1.
+	FlushEvery int
+}
+
+// Creates a stream which sends the items from the channel until it's closed.
+func NewStream[T any](items <-chan T) *Stream[T] {
+	return &Stream[T]{Items: items}
+}
+
+// Creates a stream which sends the items yielded by the function until it returns.
+func NewStreamFunc[T any](seq func(yield func(T) bool)) *Stream[T] {
+	return &Stream[T]{Seq: seq}
+}
+
+// Creates a stream which sends the items from a slice.
+func NewStreamSlice[T any](items []T) *Stream[T] {
+	return NewStreamFunc(func(yield func(T) bool) {
+		for _, item := range items {
+			if !yield(item) {
+				return
+			}
+		}
+	})
+}
+
+var _ CanStream = &Stream[string]{}
+var _ HasStatus = Stream[string]{}
+var _ HasContentTypes = Stream[string]{}
+var _ api.HasSchemaType = Stream[string]{}
+var _ api.HasName = Stream[string]{}
+
+func (s Stream[T]) HTTPStatus() int {
+	return http.StatusOK
+}
+func (s Stream[T]) HTTPStatuses() []int {
+	return []int{http.StatusOK}
+}
+func (s Stream[T]) HTTPContentTypes() []string {
+	if s.ContentType != api.ContentTypeNone {
+		return []string{string(s.ContentType)}
+	}
+	return []string{string(api.ContentTypeJSON), string(api.ContentTypeNDJSON)}
+}
+func (s Stream[T]) APISchemaType() any {
+	return []T{}
+}
+func (s Stream[T]) APIName() string {
+	return getResultAPIName([]T{}, "Stream")
+}
+func (s *Stream[T]) HTTPStream(w http.ResponseWriter, r *http.Request) error {
+	if s == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	contentType := s.ContentType
+	if contentType == api.ContentTypeNone {
+		accept := ""
+		if r != nil {
+			accept = r.Header.Get("Accept")
+		}
+		contentType, _ = negotiate(accept, toContentTypes(s.HTTPContentTypes()))
+	}
+	if contentType == api.ContentTypeNone {
+		contentType = api.ContentTypeJSON
+	}
+	ndjson := contentType == api.ContentTypeNDJSON
+
+	flusher, _ := w.(http.Flusher)
+	flushEvery := s.FlushEvery
+	if flushEvery < 1 {
+		flushEvery = 1
+	}
+
+	w.Header().Set("Content-Type", string(contentType))
+	w.Header().Set("X-Accel-Buffering", "no")
+	if s.ContentType == api.ContentTypeNone {
+		w.Header().Add("Vary", "Accept")
+	}
+	w.WriteHeader(http.StatusOK)
+
+	ctx := requestContext(r)
+	count := 0
+	var err error
+
+	write := func(data []byte) bool {
+		if _, writeErr := w.Write(data); writeErr != nil {
+			err = writeErr
+			return false
+		}
+		return true
+	}
+	send := func(item T) bool {
+		data, encodeErr := json.Marshal(item)
+		if encodeErr != nil {
+			err = encodeErr
+			return false
+		}
+		switch {
+		case ndjson:
+			data = append(data, '\n')
+		case count > 0:
+			data = append([]byte{','}, data...)
+		}
+		if !write(data) {
+			return false
+		}
+		count++
+		if flusher != nil && count%flushEvery == 0 {
+			flusher.Flush()
+		}
+		return true
+	}
+
+	if !ndjson && !write([]byte{'['}) {
+		return err
+	}
+
+	if s.Seq != nil {
+		s.Seq(func(item T) bool {
+			if ctx.Err() != nil {
+				return false
+			}
+			return send(item)
+		})
+	} else if s.Items != nil {
+	items:
+		for {
+			select {
+			case <-ctx.Done():
+				break items
+			case item, ok := <-s.Items:
+				if !ok || !send(item) {
+					break items
+				}
+			}
+		}
+	}
+
+	if err != nil || ctx.Err() != nil {
+		return err
+	}
+	if !ndjson && !write([]byte("]\n")) {
+		return err
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// Returns the context of the request. Streams sent without a request are never canceled.
+func requestContext(r *http.Request) context.Context {
+	if r == nil {
+		return context.Background()
+	}
+	return r.Context()
+}
@@ -0,0 +1,116 @@
+package rez
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ClickerMonkey/rez/api"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type testProgress struct {
+	Percent int `json:"percent"`
+}
+
+func (p testProgress) HTTPEventName() string {
+	return "progress"
+}
+
+func TestEventStream(t *testing.T) {
+	site := New(chi.NewRouter())
+
+	site.Get("/channel", func() *EventStream[testProgress] {
+		events := make(chan testProgress)
+		go func() {
+			defer close(events)
+			events <- testProgress{Percent: 50}
+			events <- testProgress{Percent: 100}
+		}()
+		return NewEventStream[testProgress](events)
+	})
+	site.Get("/seq", func() *EventStream[testProgress] {
+		return NewEventStreamFunc(func(yield func(testProgress) bool) {
+			for i := 1; i <= 2; i++ {
+				if !yield(testProgress{Percent: i * 50}) {
+					return
+				}
+			}
+		})
+	})
+
+	site.Get("/none", func() *EventStream[testProgress] {
+		return &EventStream[testProgress]{}
+	})
+
+	for _, pattern := range []string{"/channel", "/seq"} {
+		req := httptest.NewRequest("GET", pattern, nil)
+		res := httptest.NewRecorder()
+		site.Chi().ServeHTTP(res, req)
+
+		assert.Equal(t, 200, res.Code, pattern)
+		assert.Equal(t, "text/event-stream", res.Header().Get("Content-Type"), pattern)
+		assert.Empty(t, res.Header().Get("Connection"), pattern)
+		assert.True(t, res.Flushed, pattern)
+		assert.Equal(t, "event: progress\ndata: {\"percent\":50}\n\nevent: progress\ndata: {\"percent\":100}\n\n", res.Body.String(), pattern)
+	}
+
+	req := httptest.NewRequest("GET", "/none", nil)
+	res := httptest.NewRecorder()
+	site.Chi().ServeHTTP(res, req)
+	assert.Equal(t, 204, res.Code)
+	assert.Empty(t, res.Body.String())
+
+	doc := site.BuildDocument()
+	content := doc.Paths["/seq"].Get.Responses["200"].Content
+	assert.Len(t, content, 1)
+	assert.NotNil(t, content[api.ContentTypeEventStream])
+	assert.Nil(t, doc.Paths["/seq"].Get.Responses["406"])
+	noContent := doc.Paths["/seq"].Get.Responses["204"]
+	if assert.NotNil(t, noContent) {
+		assert.Equal(t, "No Content", noContent.Description)
+		assert.Empty(t, noContent.Content)
+	}
+}
+
+func TestStream(t *testing.T) {
+	site := New(chi.NewRouter())
+
+	site.Get("/items", func() *Stream[testItem] {
+		return NewStreamSlice([]testItem{{Name: "a"}, {Name: "b"}})
+	})
+	site.Get("/empty", func() *Stream[testItem] {
+		items := make(chan testItem)
+		close(items)
+		return NewStream[testItem](items)
+	})
+
+	tests := []struct {
+		pattern     string
+		accept      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"/items", "", 200, "application/json", "[{\"name\":\"a\"},{\"name\":\"b\"}]\n"},
+		{"/items", "application/x-ndjson", 200, "application/x-ndjson", "{\"name\":\"a\"}\n{\"name\":\"b\"}\n"},
+		{"/items", "text/csv", 406, "application/json", "{\"accept\":\"text/csv\",\"available\":[\"application/json\",\"application/x-ndjson\"]}\n"},
+		{"/empty", "", 200, "application/json", "[]\n"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", test.pattern, nil)
+		req.Header.Set("Accept", test.accept)
+		res := httptest.NewRecorder()
+		site.Chi().ServeHTTP(res, req)
+
+		assert.Equal(t, test.status, res.Code, test.accept)
+		assert.Equal(t, test.contentType, res.Header().Get("Content-Type"), test.accept)
+		assert.Equal(t, test.body, res.Body.String(), test.accept)
+	}
+
+	doc := site.BuildDocument()
+	content := doc.Paths["/items"].Get.Responses["200"].Content
+	assert.Len(t, content, 2)
+	assert.Equal(t, api.DataTypeArray, content[api.ContentTypeNDJSON].Schema.Type)
+}
@@ -0,0 +1,125 @@
+package rez
+
+import (
+	"encoding"
+	"reflect"
+	"strings"
+
+	"github.com/ClickerMonkey/rez/api"
+)
+
+// The shape of a parameter value, which decides how its style is parsed.
+type paramShape int
+
+const (
+	paramShapePrimitive paramShape = iota
+	paramShapeArray
+	paramShapeObject
+)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// Returns the shape of a parameter of the given type. Types which unmarshal themselves from
+// text and byte slices are primitives.
+func getParamShape(typ reflect.Type) paramShape {
+	typ = getConcrete(typ)
+	if reflect.PointerTo(typ).Implements(textUnmarshalerType) {
+		return paramShapePrimitive
+	}
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return paramShapePrimitive
+		}
+		return paramShapeArray
+	case reflect.Map, reflect.Struct:
+		return paramShapeObject
+	}
+	return paramShapePrimitive
+}
+
+// Returns the style and explode of the field as a parameter in the location, the same as
+// its documentation.
+func (field *jsonField) style(in api.ParameterIn) (api.Style, bool) {
+	style, explode := api.GetParameterStyle(field.tag.Get("api"))
+	return api.GetStyle(in, style, explode)
+}
+
+// Returns the field with the given property name, if any.
+func (jt *jsonType) field(name string) *jsonField {
+	if jt == nil {
+		return nil
+	}
+	return jt.fields[strings.ToLower(name)]
+}
+
+// Sets the node to the value of the named parameter serialized with the style. Path values
+// of the label and matrix styles have their prefix.
+//
+//	style           explode  array               object
+//	simple          false    a,b                 k1,v1,k2,v2
+//	simple          true     a,b                 k1=v1,k2=v2
+//	label           false    .a,b                .k1,v1,k2,v2
+//	label           true     .a.b                .k1=v1.k2=v2
+//	matrix          false    ;name=a,b           ;name=k1,v1,k2,v2
+//	matrix          true     ;name=a;name=b      ;k1=v1;k2=v2
+//	form            false    a,b                 k1,v1,k2,v2
+//	spaceDelimited  false    a b
+//	pipeDelimited   false    a|b
+func setStyledValue(node *queryNode, name string, shape paramShape, style api.Style, explode bool, value string) {
+	separator := ","
+	itemPrefix := ""
+
+	switch style {
+	case api.ParameterStyleLabel:
+		value = strings.TrimPrefix(value, ".")
+		if explode {
+			separator = "."
+		}
+	case api.ParameterStyleMatrix:
+		value = strings.TrimPrefix(value, ";")
+		if explode && shape != paramShapePrimitive {
+			separator = ";"
+			if shape == paramShapeArray {
+				itemPrefix = name + "="
+			}
+		} else {
+			value = strings.TrimPrefix(value, name+"=")
+		}
+	case api.ParameterStyleSpaceDelimited:
+		separator = " "
+	case api.ParameterStylePipeDelimited:
+		separator = "|"
+	}
+
+	switch shape {
+	case paramShapePrimitive:
+		node.set(value)
+	case paramShapeArray:
+		node.kind = queryNodeKindSlice
+		if value == "" {
+			return
+		}
+		for i, item := range strings.Split(value, separator) {
+			node.index(i).set(strings.TrimPrefix(item, itemPrefix))
+		}
+	case paramShapeObject:
+		node.kind = queryNodeKindObject
+		if value == "" {
+			return
+		}
+		parts := strings.Split(value, separator)
+		if explode {
+			for _, part := range parts {
+				keyValue := strings.SplitN(part, "=", 2)
+				if len(keyValue) == 2 {
+					node.property(keyValue[0]).set(keyValue[1])
+				}
+			}
+		} else {
+			for i := 0; i+1 < len(parts); i += 2 {
+				node.property(parts[i]).set(parts[i+1])
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package rez
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ClickerMonkey/rez/api"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type styleRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+type stylePage struct {
+	Size   int `json:"size"`
+	Offset int `json:"offset"`
+}
+
+type styleQuery struct {
+	Tags   []string       `json:"tags"`
+	IDs    []int          `json:"ids" api:"explode=false"`
+	Words  []string       `json:"words" api:"style=spaceDelimited"`
+	Pipes  []string       `json:"pipes" api:"style=pipeDelimited"`
+	Filter styleRange     `json:"filter" api:"style=deepObject"`
+	Point  styleRange     `json:"point" api:"explode=false"`
+	Page   stylePage      `json:"page"`
+	Labels map[string]int `json:"labels" api:"explode=false"`
+	Limit  int            `json:"limit"`
+}
+
+type stylePath struct {
+	Simple   []int      `json:"simple"`
+	Label    []string   `json:"label" api:"style=label"`
+	Matrix   []string   `json:"matrix" api:"style=matrix,explode"`
+	Exploded styleRange `json:"exploded" api:"explode=true"`
+	ID       int        `json:"id" api:"style=matrix"`
+}
+
+type styleHeader struct {
+	IDs   []int      `json:"X-Ids"`
+	Range styleRange `json:"X-Range" api:"explode"`
+}
+
+type styleCookie struct {
+	Tags  []string   `json:"tags"`
+	IDs   []int      `json:"ids" api:"explode=false"`
+	Range styleRange `json:"range" api:"explode=false"`
+	Page  stylePage  `json:"page"`
+	Limit int        `json:"limit"`
+}
+
+func TestParameterStyles(t *testing.T) {
+	site := New(chi.NewRouter())
+
+	var query styleQuery
+	site.Get("/query", func(q Query[styleQuery]) {
+		query = q.Value
+	})
+	var path stylePath
+	site.Get("/path/{simple}/{label}/{matrix}/{exploded}/{id}", func(p Path[stylePath]) {
+		path = p.Value
+	})
+	var header styleHeader
+	site.Get("/header", func(h Header[styleHeader]) {
+		header = h.Value
+	})
+	var cookie styleCookie
+	site.Get("/cookie", func(c Cookie[styleCookie]) {
+		cookie = c.Value
+	})
+
+	serve := func(request *http.Request) {
+		w := httptest.NewRecorder()
+		site.router.ServeHTTP(w, request)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	serve(httptest.NewRequest(http.MethodGet, "/query?tags=a&tags=b,c&ids=1,2&words=x%20y&pipes=p|q&filter[min]=1&filter[max]=2&point=min,3,max,4&size=10&offset=20&labels=x,1,y,2&limit=5", nil))
+	assert.Equal(t, styleQuery{
+		Tags:   []string{"a", "b,c"},
+		IDs:    []int{1, 2},
+		Words:  []string{"x", "y"},
+		Pipes:  []string{"p", "q"},
+		Filter: styleRange{Min: 1, Max: 2},
+		Point:  styleRange{Min: 3, Max: 4},
+		Page:   stylePage{Size: 10, Offset: 20},
+		Labels: map[string]int{"x": 1, "y": 2},
+		Limit:  5,
+	}, query)
+
+	serve(httptest.NewRequest(http.MethodGet, "/path/1,2/.a,b/;matrix=c;matrix=d/min=5,max=6/;id=7", nil))
+	assert.Equal(t, stylePath{
+		Simple:   []int{1, 2},
+		Label:    []string{"a", "b"},
+		Matrix:   []string{"c", "d"},
+		Exploded: styleRange{Min: 5, Max: 6},
+		ID:       7,
+	}, path)
+
+	request := httptest.NewRequest(http.MethodGet, "/header", nil)
+	request.Header.Add("X-Ids", "1,2")
+	request.Header.Add("X-Ids", "3")
+	request.Header.Set("X-Range", "min=8,max=9")
+	serve(request)
+	assert.Equal(t, styleHeader{
+		IDs:   []int{1, 2, 3},
+		Range: styleRange{Min: 8, Max: 9},
+	}, header)
+
+	request = httptest.NewRequest(http.MethodGet, "/cookie", nil)
+	request.AddCookie(&http.Cookie{Name: "tags", Value: "a"})
+	request.AddCookie(&http.Cookie{Name: "tags", Value: "b"})
+	request.AddCookie(&http.Cookie{Name: "ids", Value: "1,2"})
+	request.AddCookie(&http.Cookie{Name: "range", Value: "min,3,max,4"})
+	request.AddCookie(&http.Cookie{Name: "size", Value: "10"})
+	request.AddCookie(&http.Cookie{Name: "offset", Value: "20"})
+	request.AddCookie(&http.Cookie{Name: "limit", Value: "5"})
+	serve(request)
+	assert.Equal(t, styleCookie{
+		Tags:  []string{"a", "b"},
+		IDs:   []int{1, 2},
+		Range: styleRange{Min: 3, Max: 4},
+		Page:  stylePage{Size: 10, Offset: 20},
+		Limit: 5,
+	}, cookie)
+
+	doc := site.BuildDocument()
+	params := map[string]api.Parameter{}
+	for _, param := range doc.Paths["/query"].Get.Parameters {
+		params[param.Name] = param
+	}
+	for _, param := range doc.Paths["/path/{simple}/{label}/{matrix}/{exploded}/{id}"].Get.Parameters {
+		params[param.Name] = param
+	}
+	assert.JSONEq(t, `{"name":"ids","in":"query","required":true,"explode":false,"schema":{"type":"array","items":{"type":"integer"}}}`, toJSON(params["ids"]))
+	assert.Equal(t, api.ParameterStyleSpaceDelimited, params["words"].Style)
+	assert.Equal(t, api.ParameterStyleDeepObject, params["filter"].Style)
+	assert.True(t, *params["filter"].Explode)
+	assert.Nil(t, params["tags"].Explode)
+	assert.Equal(t, api.ParameterStyleMatrix, params["matrix"].Style)
+	assert.True(t, *params["matrix"].Explode)
+
+	style, explode := params["tags"].GetStyle()
+	assert.Equal(t, api.ParameterStyleForm, style)
+	assert.True(t, explode)
+	style, explode = params["simple"].GetStyle()
+	assert.Equal(t, api.ParameterStyleSimple, style)
+	assert.False(t, explode)
+
+	assert.PanicsWithValue(t, "path parameter id can't have the form style", func() {
+		site.Get("/invalid/{id}", func(p Path[struct {
+			ID int `json:"id" api:"style=form"`
+		}]) {
+		})
+	})
+}
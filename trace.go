@@ -0,0 +1,374 @@
+package rez
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ClickerMonkey/deps"
+	"github.com/ClickerMonkey/rez/api"
+	"github.com/go-chi/chi/v5"
+)
+
+// Receives the spans of the phases of handling a request, to send them to a tracing system
+// like OpenTelemetry. Spans are started and ended in the goroutine of the request and are
+// nested: a request span contains middleware and handler spans, and injection spans are within
+// the middleware or handler that requested the value.
+type Tracer interface {
+	// Called when a span starts with the context of its parent span. The returned context
+	// is the context of the span, which is given to the phase and to EndSpan.
+	StartSpan(ctx context.Context, span *Span) context.Context
+	// Called when a span ends with the context returned by StartSpan.
+	EndSpan(ctx context.Context, span *Span)
+}
+
+// A phase of handling a request.
+type SpanKind string
+
+const (
+	// The whole request, from the first middleware of the router to the response being sent.
+	SpanKindRequest SpanKind = "request"
+	// A middleware function, including the handlers it calls with next.
+	SpanKindMiddleware SpanKind = "middleware"
+	// Parsing and validating a part of the request (body, path, query, header, cookie, or files)
+	// for an argument of a middleware or handler.
+	SpanKindInjection SpanKind = "injection"
+	// The handler function of an operation, including the injection of its arguments.
+	SpanKindHandler SpanKind = "handler"
+	// Encoding and sending the response or error of an operation.
+	SpanKindSend SpanKind = "send"
+)
+
+// A phase of handling a request given to a Tracer.
+type Span struct {
+	// The phase of the request.
+	Kind SpanKind
+	// The name of the span. For requests it's the method until the span ends, then it's the
+	// method and route pattern. For middleware and handlers it's the name of the function and
+	// for injections it's the part of the request.
+	Name string
+	// The trace context of this span.
+	Trace TraceContext
+	// The trace context of the parent span. For requests it's from the traceparent header.
+	Parent TraceContext
+	// The method of the request.
+	Method string
+	// The route pattern matched, set when the span ends.
+	Route string
+	// The id of the operation handling the request, set when the span ends.
+	OperationID string
+	// The status sent, set when the request span ends.
+	Status int
+	// The number of validation failures, set when injection and request spans end.
+	ValidationFailures int
+	// The error returned by the phase, set when the span ends.
+	Err error
+	// When the span started.
+	Start time.Time
+	// How long the span took, set when the span ends.
+	Duration time.Duration
+}
+
+// Sets the tracer given the spans of requests to this router and sub routers created after this
+// is set. Requests are only traced when there's a tracer. The traceparent and tracestate headers
+// of a traced request are the parent of its spans, and the TraceContext of the current span is
+// in the context.Context given to middleware and handlers.
+func (site *Site) SetTracer(tracer Tracer) {
+	site.tracer = tracer
+}
+
+// A W3C trace context (https://www.w3.org/TR/trace-context/).
+type TraceContext struct {
+	// The id of the whole trace.
+	TraceID [16]byte
+	// The id of the span.
+	SpanID [8]byte
+	// The trace flags, the lowest bit is whether the trace is sampled.
+	Flags byte
+	// The vendor specific tracestate header.
+	State string
+}
+
+// Parses the value of a traceparent header. Returns an empty context and false if it's not valid.
+func ParseTraceParent(traceparent string) (TraceContext, bool) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return TraceContext{}, false
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return TraceContext{}, false
+	}
+	tc := TraceContext{}
+	version, flags := [1]byte{}, [1]byte{}
+	if !decodeTraceHex(version[:], parts[0]) ||
+		!decodeTraceHex(tc.TraceID[:], parts[1]) ||
+		!decodeTraceHex(tc.SpanID[:], parts[2]) ||
+		!decodeTraceHex(flags[:], parts[3]) ||
+		!tc.IsValid() {
+		return TraceContext{}, false
+	}
+	tc.Flags = flags[0]
+	return tc, true
+}
+
+// Decodes lowercase hex of exactly the length of out.
+func decodeTraceHex(out []byte, s string) bool {
+	if len(s) != len(out)*2 || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(out, []byte(s))
+	return err == nil
+}
+
+// Returns whether the trace and span ids are set.
+func (tc TraceContext) IsValid() bool {
+	return tc.TraceID != [16]byte{} && tc.SpanID != [8]byte{}
+}
+
+// Returns whether the trace is sampled.
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&1 == 1
+}
+
+// Returns the traceparent header value of the trace context.
+func (tc TraceContext) TraceParent() string {
+	return "00-" + hex.EncodeToString(tc.TraceID[:]) + "-" + hex.EncodeToString(tc.SpanID[:]) + "-" + hex.EncodeToString([]byte{tc.Flags})
+}
+
+// Sets the traceparent and tracestate headers so the trace continues in the service the request is sent to.
+func (tc TraceContext) Inject(header http.Header) {
+	if !tc.IsValid() {
+		return
+	}
+	header.Set("traceparent", tc.TraceParent())
+	if tc.State != "" {
+		header.Set("tracestate", tc.State)
+	} else {
+		header.Del("tracestate")
+	}
+}
+
+// Returns a trace context for a span which is a child of this one. If this isn't valid
+// a new sampled trace is started.
+func (tc TraceContext) child() TraceContext {
+	child := tc
+	if !tc.IsValid() {
+		_, _ = rand.Read(child.TraceID[:])
+		child.Flags = 1
+	}
+	_, _ = rand.Read(child.SpanID[:])
+	return child
+}
+
+type traceKey struct {
+	key string
+}
+
+var traceContextKey = traceKey{"RezTraceContext"}
+var requestTraceKey = traceKey{"RezRequestTrace"}
+
+// Returns a copy of the context with the trace context.
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey, tc)
+}
+
+// Returns the trace context in the context, if any.
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceContextKey).(TraceContext)
+	return tc, ok
+}
+
+// The trace of a request being handled.
+type requestTrace struct {
+	tracer Tracer
+	span   *Span
+	route  *chi.Context
+	op     *api.Operation
+	failed int
+}
+
+// Starts the request span if there's a tracer and the request isn't being traced yet. Returns
+// the writer and request to handle the request with and the function which ends the span.
+func (site *Site) traceRequest(w http.ResponseWriter, request *http.Request) (http.ResponseWriter, *http.Request, func()) {
+	if site.tracer == nil || request.Context().Value(requestTraceKey) != nil {
+		return w, request, func() {}
+	}
+
+	parent, ok := ParseTraceParent(request.Header.Get("traceparent"))
+	if ok {
+		parent.State = request.Header.Get("tracestate")
+	}
+	span := &Span{
+		Kind:   SpanKindRequest,
+		Name:   request.Method,
+		Trace:  parent.child(),
+		Parent: parent,
+		Method: request.Method,
+		Start:  time.Now(),
+	}
+	trace := &requestTrace{
+		tracer: site.tracer,
+		span:   span,
+		route:  chi.RouteContext(request.Context()),
+	}
+
+	ctx := context.WithValue(request.Context(), requestTraceKey, trace)
+	ctx = ContextWithTrace(ctx, span.Trace)
+	ctx = trace.tracer.StartSpan(ctx, span)
+
+	recorder := &statusRecorder{ResponseWriter: w}
+
+	return recorder, request.WithContext(ctx), func() {
+		span.Status = recorder.status
+		if span.Status == 0 {
+			span.Status = http.StatusOK
+		}
+		span.ValidationFailures = trace.failed
+		trace.annotate(span)
+		if span.Route != "" {
+			span.Name = span.Method + " " + span.Route
+		}
+		span.Duration = time.Since(span.Start)
+		trace.tracer.EndSpan(ctx, span)
+	}
+}
+
+// Sets the route and operation id of the span.
+func (trace *requestTrace) annotate(span *Span) {
+	if trace.route != nil {
+		span.Route = trace.route.RoutePattern()
+	}
+	if trace.op != nil {
+		span.OperationID = trace.op.OperationID
+	}
+}
+
+// Returns the trace of the request of the scope and the scope's context, if the request is being traced.
+func getRequestTrace(scope *deps.Scope) (*requestTrace, *context.Context) {
+	if scope == nil {
+		return nil, nil
+	}
+	ctx, _ := deps.GetScoped[context.Context](scope)
+	if ctx == nil || *ctx == nil {
+		return nil, nil
+	}
+	trace, _ := (*ctx).Value(requestTraceKey).(*requestTrace)
+	return trace, ctx
+}
+
+// Sets the operation handling the request of the scope, if it's being traced.
+func traceOperation(scope *deps.Scope, op *api.Operation) {
+	if trace, _ := getRequestTrace(scope); trace != nil {
+		trace.op = op
+	}
+}
+
+// A span which has started and hasn't ended.
+type activeSpan struct {
+	trace           *requestTrace
+	span            *Span
+	ctx             context.Context
+	scope           *deps.Scope
+	previousCtx     context.Context
+	previousRequest *http.Request
+}
+
+// Starts a span in the trace of the request of the scope, if it's being traced. The context
+// and request in the scope are the span's until it ends.
+func startSpan(scope *deps.Scope, kind SpanKind, name string) *activeSpan {
+	trace, ctx := getRequestTrace(scope)
+	if trace == nil {
+		return nil
+	}
+
+	parent, _ := TraceFromContext(*ctx)
+	span := &Span{
+		Kind:   kind,
+		Name:   name,
+		Trace:  parent.child(),
+		Parent: parent,
+		Method: trace.span.Method,
+		Start:  time.Now(),
+	}
+	active := &activeSpan{
+		trace:       trace,
+		span:        span,
+		scope:       scope,
+		previousCtx: *ctx,
+	}
+
+	spanCtx := ContextWithTrace(*ctx, span.Trace)
+	spanCtx = trace.tracer.StartSpan(spanCtx, span)
+	active.ctx = spanCtx
+
+	deps.SetScoped(scope, &spanCtx)
+	if request, _ := deps.GetScoped[http.Request](scope); request != nil {
+		active.previousRequest = request
+		deps.SetScoped(scope, request.WithContext(spanCtx))
+	}
+
+	return active
+}
+
+// Ends the span with the error returned by the phase, if any.
+func (active *activeSpan) end(err error) {
+	if active == nil {
+		return
+	}
+	span := active.span
+	span.Err = err
+	active.trace.failed += span.ValidationFailures
+	active.trace.annotate(span)
+	span.Duration = time.Since(span.Start)
+	active.trace.tracer.EndSpan(active.ctx, span)
+
+	previousCtx := active.previousCtx
+	deps.SetScoped(active.scope, &previousCtx)
+	if active.previousRequest != nil {
+		deps.SetScoped(active.scope, active.previousRequest)
+	}
+}
+
+// Provides a part of the request in an injection span.
+func traceInjection(scope *deps.Scope, name string, provide func() error) error {
+	span := startSpan(scope, SpanKindInjection, name)
+	if span == nil {
+		return provide()
+	}
+	before := validationFailures(scope)
+	err := provide()
+	span.span.ValidationFailures = validationFailures(scope) - before
+	span.end(err)
+	return err
+}
+
+// Returns the number of validation failures of the request of the scope so far.
+func validationFailures(scope *deps.Scope) int {
+	validator, _ := deps.GetScoped[Validator](scope)
+	if validator == nil || validator.Validations == nil {
+		return 0
+	}
+	return len(*validator.Validations)
+}
+
+// Returns the name of the function for spans, like rez.getTask.
+func funcName(fn any) string {
+	value := reflect.ValueOf(fn)
+	if value.Kind() != reflect.Func {
+		return ""
+	}
+	f := runtime.FuncForPC(value.Pointer())
+	if f == nil {
+		return ""
+	}
+	name := f.Name()
+	if i := strings.LastIndex(name, "/"); i != -1 {
+		name = name[i+1:]
+	}
+	return name
+}
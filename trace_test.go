@@ -0,0 +1,166 @@
+package rez
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ClickerMonkey/rez/api"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type testTracer struct {
+	started []*Span
+	ended   []*Span
+}
+
+type testSpanKey struct{}
+
+func (t *testTracer) StartSpan(ctx context.Context, span *Span) context.Context {
+	t.started = append(t.started, span)
+	return context.WithValue(ctx, testSpanKey{}, span)
+}
+
+func (t *testTracer) EndSpan(ctx context.Context, span *Span) {
+	if ctx.Value(testSpanKey{}) != span {
+		panic("span ended with the wrong context")
+	}
+	t.ended = append(t.ended, span)
+}
+
+func TestTracer(t *testing.T) {
+	tracer := &testTracer{}
+	site := New(chi.NewRouter())
+	site.EnableValidation(true)
+	site.SetTracer(tracer)
+
+	site.Use(func(next MiddlewareNext) {
+		next()
+	})
+
+	var handlerTrace TraceContext
+	var handlerCtxSpan any
+	site.Post("/tasks/{id}", func(ctx context.Context, p Path[struct{ ID int }], b Body[testTask]) testTask {
+		handlerTrace, _ = TraceFromContext(ctx)
+		handlerCtxSpan = ctx.Value(testSpanKey{})
+		return b.Value
+	}, api.Operation{OperationID: "updateTask"})
+
+	request := httptest.NewRequest(http.MethodPost, "/tasks/1", strings.NewReader(`{"name":"task","tags":[]}`))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	request.Header.Set("tracestate", "congo=t61rcWkgMzE")
+	w := httptest.NewRecorder()
+	site.router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	kinds := func(spans []*Span) []string {
+		names := []string{}
+		for _, span := range spans {
+			names = append(names, string(span.Kind)+" "+span.Name)
+		}
+		return names
+	}
+	assert.Equal(t, []string{
+		"request POST /tasks/{id}",
+		"middleware rez.TestTracer.func1",
+		"handler rez.TestTracer.func2",
+		"injection path",
+		"injection body",
+		"send send",
+	}, kinds(tracer.started))
+	assert.Equal(t, []string{
+		"injection path",
+		"injection body",
+		"handler rez.TestTracer.func2",
+		"send send",
+		"middleware rez.TestTracer.func1",
+		"request POST /tasks/{id}",
+	}, kinds(tracer.ended))
+
+	spans := tracer.started
+	requestSpan, middlewareSpan, handlerSpan, pathSpan := spans[0], spans[1], spans[2], spans[3]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", traceParentIDs(requestSpan.Parent))
+	assert.Equal(t, "congo=t61rcWkgMzE", requestSpan.Trace.State)
+	assert.Equal(t, requestSpan.Parent.TraceID, requestSpan.Trace.TraceID)
+	assert.NotEqual(t, requestSpan.Parent.SpanID, requestSpan.Trace.SpanID)
+	assert.Equal(t, requestSpan.Trace, middlewareSpan.Parent)
+	assert.Equal(t, middlewareSpan.Trace, handlerSpan.Parent)
+	assert.Equal(t, handlerSpan.Trace, pathSpan.Parent)
+	assert.Equal(t, handlerSpan.Trace, handlerTrace)
+	assert.Equal(t, handlerSpan, handlerCtxSpan)
+
+	for _, span := range spans {
+		assert.Equal(t, "/tasks/{id}", span.Route)
+		assert.Equal(t, "updateTask", span.OperationID)
+		assert.Equal(t, http.MethodPost, span.Method)
+	}
+	assert.Equal(t, http.StatusOK, requestSpan.Status)
+
+	tracer.started, tracer.ended = nil, nil
+	request = httptest.NewRequest(http.MethodPost, "/tasks/1", strings.NewReader(`{"name":"too long","tags":[]}`))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz")
+	request.Header.Set("tracestate", "congo=t61rcWkgMzE")
+	w = httptest.NewRecorder()
+	site.router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	requestSpan = tracer.started[0]
+	assert.Equal(t, TraceContext{}, requestSpan.Parent)
+	assert.True(t, requestSpan.Trace.IsValid())
+	assert.NotEqual(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceParentIDs(requestSpan.Trace)[:32])
+	assert.Empty(t, requestSpan.Trace.State)
+	assert.True(t, requestSpan.Trace.Sampled())
+	assert.Equal(t, http.StatusBadRequest, requestSpan.Status)
+	assert.Equal(t, 1, requestSpan.ValidationFailures)
+	bodySpan := tracer.started[4]
+	assert.Equal(t, "body", bodySpan.Name)
+	assert.Equal(t, 1, bodySpan.ValidationFailures)
+	assert.Error(t, bodySpan.Err)
+	assert.Equal(t, 0, tracer.started[3].ValidationFailures)
+}
+
+func traceParentIDs(tc TraceContext) string {
+	parts := strings.Split(tc.TraceParent(), "-")
+	return parts[1] + "-" + parts[2]
+}
+
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		header string
+		valid  bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0g", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		tc, valid := ParseTraceParent(test.header)
+		assert.Equal(t, test.valid, valid, test.header)
+		if valid && strings.HasPrefix(test.header, "00") {
+			assert.Equal(t, test.header, tc.TraceParent())
+		} else if !valid {
+			assert.Equal(t, TraceContext{}, tc, test.header)
+		}
+	}
+
+	tc, _ := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	tc.State = "congo=t61rcWkgMzE"
+	header := http.Header{}
+	tc.Inject(header)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", header.Get("traceparent"))
+	assert.Equal(t, "congo=t61rcWkgMzE", header.Get("tracestate"))
+}
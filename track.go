@@ -0,0 +1,252 @@
+package rez
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ClickerMonkey/rez/api"
+	"github.com/go-chi/chi/v5"
+)
+
+// How the responses sent by operations are tracked.
+type ResponseTracking int
+
+const (
+	// Responses are not tracked, this is the default.
+	ResponseTrackingOff ResponseTracking = iota
+	// The status and content type of each response is recorded, see Site.SentResponses.
+	ResponseTrackingRecord
+	// Responses are recorded and the first time an undocumented status or content type is
+	// sent by an operation an UndocumentedResponse is given to the InternalErrorHandler.
+	ResponseTrackingReport
+	// Responses are recorded and sending an undocumented status or content type panics
+	// with an UndocumentedResponse after the response is sent. Meant for development and tests.
+	ResponseTrackingPanic
+)
+
+// A status and content type sent by an operation.
+type SentResponse struct {
+	// The method of the operation.
+	Method string
+	// The route pattern of the operation.
+	Path string
+	// The status sent.
+	Status int
+	// The content type sent, if there was a body.
+	ContentType string
+	// How many times the status and content type were sent.
+	Count int
+	// If the status and content type are documented on the operation.
+	Documented bool
+}
+
+// A response sent by an operation which does not have its status or content type documented.
+type UndocumentedResponse struct {
+	// The method of the operation.
+	Method string
+	// The route pattern of the operation.
+	Path string
+	// The status sent.
+	Status int
+	// The content type sent, if there was a body.
+	ContentType string
+	// If the status is documented and the content type is not.
+	StatusDocumented bool
+}
+
+var _ error = UndocumentedResponse{}
+
+// UndocumentedResponse implements error
+func (e UndocumentedResponse) Error() string {
+	if e.StatusDocumented {
+		return fmt.Sprintf("%s %s sent a %d response with the undocumented content type %s", e.Method, e.Path, e.Status, e.ContentType)
+	}
+	return fmt.Sprintf("%s %s sent the undocumented status %d", e.Method, e.Path, e.Status)
+}
+
+// Sets how the responses sent by operations are tracked. This affects the whole site,
+// including routers created before this call.
+func (site *Site) TrackResponses(tracking ResponseTracking) {
+	site.tracker.mutex.Lock()
+	defer site.tracker.mutex.Unlock()
+
+	site.tracker.tracking = tracking
+}
+
+// Returns the responses sent by each operation since tracking was enabled, sorted by
+// path, method, status, and content type.
+func (site *Site) SentResponses() []SentResponse {
+	site.tracker.mutex.Lock()
+	defer site.tracker.mutex.Unlock()
+
+	sent := make([]SentResponse, 0, len(site.tracker.sent))
+	for _, response := range site.tracker.sent {
+		sent = append(sent, *response)
+	}
+	sort.Slice(sent, func(i, j int) bool {
+		a, b := sent[i], sent[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		if a.Status != b.Status {
+			return a.Status < b.Status
+		}
+		return a.ContentType < b.ContentType
+	})
+	return sent
+}
+
+// Returns the sent responses which are not documented, see SentResponses.
+func (site *Site) UndocumentedResponses() []SentResponse {
+	undocumented := make([]SentResponse, 0)
+	for _, response := range site.SentResponses() {
+		if !response.Documented {
+			undocumented = append(undocumented, response)
+		}
+	}
+	return undocumented
+}
+
+// The responses sent by operations, shared by a site and its sub routers.
+type responseTracker struct {
+	mutex    sync.Mutex
+	tracking ResponseTracking
+	sent     map[sentKey]*SentResponse
+}
+
+type sentKey struct {
+	method      string
+	path        string
+	status      int
+	contentType string
+}
+
+func (t *responseTracker) enabled() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.tracking != ResponseTrackingOff
+}
+
+// Records the response and returns what's undocumented about it, if anything.
+// Undocumented responses are only returned the first time they are sent unless tracking panics.
+func (t *responseTracker) record(op *api.Operation, w *statusRecorder, request *http.Request) (*UndocumentedResponse, ResponseTracking) {
+	path := request.URL.Path
+	if route := chi.RouteContext(request.Context()); route != nil && route.RoutePattern() != "" {
+		path = route.RoutePattern()
+	}
+	contentType := ""
+	if w.wroteBody {
+		contentType = strings.TrimSpace(strings.SplitN(w.Header().Get("Content-Type"), ";", 2)[0])
+	}
+	key := sentKey{request.Method, path, w.status, contentType}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.sent == nil {
+		t.sent = make(map[sentKey]*SentResponse)
+	}
+	sent := t.sent[key]
+	first := sent == nil
+	if first {
+		statusDocumented, contentTypeDocumented := isDocumented(op, w.status, contentType)
+		sent = &SentResponse{
+			Method:      key.method,
+			Path:        key.path,
+			Status:      key.status,
+			ContentType: key.contentType,
+			Documented:  statusDocumented && contentTypeDocumented,
+		}
+		t.sent[key] = sent
+	}
+	sent.Count++
+
+	if sent.Documented || (!first && t.tracking != ResponseTrackingPanic) {
+		return nil, t.tracking
+	}
+
+	statusDocumented, _ := isDocumented(op, w.status, contentType)
+	return &UndocumentedResponse{
+		Method:           sent.Method,
+		Path:             sent.Path,
+		Status:           sent.Status,
+		ContentType:      sent.ContentType,
+		StatusDocumented: statusDocumented,
+	}, t.tracking
+}
+
+// Returns whether the status and the content type are documented on the operation. The
+// status can be documented exactly, by its range (like 4XX), or by the default response.
+func isDocumented(op *api.Operation, status int, contentType string) (statusDocumented bool, contentTypeDocumented bool) {
+	code := strconv.Itoa(status)
+	response := op.Responses[code]
+	if response == nil {
+		response = op.Responses[code[:1]+"XX"]
+	}
+	if response == nil {
+		response = op.Responses[code[:1]+"xx"]
+	}
+	if response == nil {
+		response = op.Responses["default"]
+	}
+	if response == nil {
+		return false, false
+	}
+	if contentType == "" {
+		return true, true
+	}
+
+	documented := make([]string, 0, len(response.Content))
+	for ct := range response.Content {
+		documented = append(documented, string(ct))
+	}
+	return true, acceptQuality(parseAccept(strings.Join(documented, ",")), api.ContentType(contentType)) >= 0
+}
+
+// A response writer which remembers the status, if a body was written, and its size.
+type statusRecorder struct {
+	http.ResponseWriter
+	status    int
+	wroteBody bool
+	size      int64
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	if len(data) > 0 {
+		r.wroteBody = true
+	}
+	n, err := r.ResponseWriter.Write(data)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *statusRecorder) Flush() {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,75 @@
+package rez
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ClickerMonkey/rez/api"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackResponses(t *testing.T) {
+	site := New(chi.NewRouter())
+	reported := []error{}
+	site.SetInternalErrorHandler(func(err error) {
+		reported = append(reported, err)
+	})
+
+	site.Get("/items/{name}", func(p Path[testItem]) (*testItem, *NotFound[string]) {
+		if p.Value.Name == "missing" {
+			return nil, NewNotFound("missing")
+		}
+		return &p.Value, nil
+	})
+	site.Get("/teapot", func() *Result[string] {
+		return NewResult(http.StatusTeapot, "short and stout")
+	})
+	site.Get("/csv", func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Write([]byte("name\nx\n"))
+	}, api.Operation{
+		Responses: api.Responses{
+			"200": {Description: "The items", Content: api.Contents{api.ContentTypeJSON: {}}},
+		},
+	})
+
+	serve := func(path string) {
+		site.Chi().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	serve("/teapot")
+	assert.Empty(t, site.SentResponses())
+
+	site.TrackResponses(ResponseTrackingReport)
+
+	serve("/items/x")
+	serve("/items/y")
+	serve("/items/missing")
+	serve("/teapot")
+	serve("/teapot")
+	serve("/csv")
+
+	assert.Equal(t, []SentResponse{
+		{Method: "GET", Path: "/csv", Status: 200, ContentType: "text/csv", Count: 1},
+		{Method: "GET", Path: "/items/{name}", Status: 200, ContentType: "application/json", Count: 2, Documented: true},
+		{Method: "GET", Path: "/items/{name}", Status: 404, ContentType: "application/json", Count: 1, Documented: true},
+		{Method: "GET", Path: "/teapot", Status: 418, ContentType: "application/json", Count: 2},
+	}, site.SentResponses())
+	assert.Len(t, site.UndocumentedResponses(), 2)
+
+	assert.Equal(t, []error{
+		UndocumentedResponse{Method: "GET", Path: "/teapot", Status: 418, ContentType: "application/json"},
+		UndocumentedResponse{Method: "GET", Path: "/csv", Status: 200, ContentType: "text/csv", StatusDocumented: true},
+	}, reported)
+	assert.Equal(t, "GET /teapot sent the undocumented status 418", reported[0].Error())
+	assert.Equal(t, "GET /csv sent a 200 response with the undocumented content type text/csv", reported[1].Error())
+
+	site.TrackResponses(ResponseTrackingPanic)
+
+	assert.NotPanics(t, func() { serve("/items/x") })
+	assert.PanicsWithValue(t, UndocumentedResponse{Method: "GET", Path: "/teapot", Status: 418, ContentType: "application/json"}, func() {
+		serve("/teapot")
+	})
+}
@@ -27,23 +27,23 @@ type ValidationOptions struct {
 // A validation failure
 type Validation struct {
 	// The path to the offending value.
-	Path []string `json:"path,omitempty"`
+	Path []string `json:"path,omitempty" xml:"path,omitempty"`
 	// The name of the schema, if any.
-	Schema *string `json:"schema,omitempty"`
+	Schema *string `json:"schema,omitempty" xml:"schema,omitempty"`
 	// The validation rule that caused the failure.
-	Rule ValidationRule `json:"rule,omitempty"`
+	Rule ValidationRule `json:"rule,omitempty" xml:"rule,omitempty"`
 	// A message with more details.
-	Message string `json:"message,omitempty"`
+	Message string `json:"message,omitempty" xml:"message,omitempty"`
 }
 
 // A validator for a specific element being validated.
 // Validators all share Validations and Providers. Adding a validation
 // to one adds it to the others.
 type Validator struct {
-	Path        []string           `json:"-"`
-	Validations *[]Validation      `json:"validations"`
-	Provider    ValidationProvider `json:"-"`
-	Scope       *deps.Scope        `json:"-"`
+	Path        []string           `json:"-" xml:"-"`
+	Validations *[]Validation      `json:"validations" xml:"validations>validation"`
+	Provider    ValidationProvider `json:"-" xml:"-"`
+	Scope       *deps.Scope        `json:"-" xml:"-"`
 }
 
 var _ error = Validator{}
@@ -71,8 +71,9 @@ func (v Validator) HTTPStatuses() []int {
 
 // Returns a child validator with the added path node. Validations are shared.
 func (v Validator) Next(path string) *Validator {
+	next := make([]string, 0, len(v.Path)+1)
 	return &Validator{
-		Path:        append(v.Path[:], path),
+		Path:        append(append(next, v.Path...), path),
 		Validations: v.Validations,
 		Provider:    v.Provider,
 		Scope:       v.Scope,
@@ -157,6 +158,8 @@ const (
 	ValidationRuleAnyOf         ValidationRule = "anyOf"
 	ValidationRuleNot           ValidationRule = "not"
 	ValidationRuleCustom        ValidationRule = "custom"
+	ValidationRuleMaxDepth      ValidationRule = "maxDepth"
+	ValidationRuleUnknownField  ValidationRule = "unknownField"
 )
 
 // Creates a new validator for the given provider and scope.
@@ -530,3 +533,130 @@ func isZero(val reflect.Value) bool {
 func isTextuallyEqual(x any, y any) bool {
 	return toString(x) == toString(y)
 }
+
+// How responses are validated against the schema documented for their status.
+type ResponseValidation int
+
+const (
+	// Responses are not validated, this is the default.
+	ResponseValidationOff ResponseValidation = iota
+	// Responses that don't match their documentation are still sent and an
+	// InvalidResponse is given to the InternalErrorHandler.
+	ResponseValidationReport
+	// Responses that don't match their documentation are given to the
+	// InternalErrorHandler and a 500 with the InvalidResponse is sent instead.
+	ResponseValidationStrict
+)
+
+// A response which does not match the schema documented for its status.
+type InvalidResponse struct {
+	// The method of the request.
+	Method string `json:"method" xml:"method"`
+	// The path of the request.
+	Path string `json:"path" xml:"path"`
+	// The status of the response.
+	Status int `json:"status" xml:"status"`
+	// The validation failures of the response.
+	Validations []Validation `json:"validations" xml:"validations>validation"`
+}
+
+var _ error = InvalidResponse{}
+
+// InvalidResponse implements error
+func (e InvalidResponse) Error() string {
+	failures := make([]string, len(e.Validations))
+	for i, failure := range e.Validations {
+		at := "response"
+		if len(failure.Path) > 0 {
+			at = strings.Join(failure.Path, ".")
+		}
+		if failure.Message != "" {
+			failures[i] = at + ": " + failure.Message
+		} else {
+			failures[i] = at + ": " + string(failure.Rule)
+		}
+	}
+	return fmt.Sprintf("%s %s responded %d which does not match its documentation: %s", e.Method, e.Path, e.Status, strings.Join(failures, ", "))
+}
+
+// Validates the response against the schema documented for its status and returns the
+// failures, if any. Streamed responses, responses with custom sending, and errors without
+// a status are not validated.
+func validateResponse(op *api.Operation, response any, request *http.Request, provider ValidationProvider, scope *deps.Scope) *InvalidResponse {
+	if op == nil || response == nil {
+		return nil
+	}
+	if _, ok := response.(CanStream); ok {
+		return nil
+	}
+	if _, ok := response.(CanSend); ok {
+		return nil
+	}
+
+	status := http.StatusOK
+	if hasStatus, ok := response.(HasStatus); ok {
+		status = hasStatus.HTTPStatus()
+	} else if _, isError := response.(error); isError {
+		return nil
+	}
+
+	schema := getResponseSchema(op, status)
+	if schema == nil {
+		return nil
+	}
+
+	value := response
+	if hasSchemaType, ok := value.(api.HasSchemaType); ok {
+		if schemaValue := hasSchemaType.APISchemaType(); !isReflectType(schemaValue) {
+			value = schemaValue
+		}
+	}
+
+	v := NewValidator(provider, scope)
+	Validate(schema, value, v)
+	if v.IsValid() {
+		return nil
+	}
+
+	return &InvalidResponse{
+		Method:      request.Method,
+		Path:        request.URL.Path,
+		Status:      status,
+		Validations: *v.Validations,
+	}
+}
+
+func isReflectType(x any) bool {
+	_, isType := x.(reflect.Type)
+	return isType
+}
+
+// Returns the documented schema of the response with the given status, or the default
+// response. All content types share the same schema, JSON is preferred if it exists.
+func getResponseSchema(op *api.Operation, status int) *api.Schema {
+	response := op.Responses[strconv.Itoa(status)]
+	if response == nil {
+		response = op.Responses["default"]
+	}
+	if response == nil || response.Content == nil {
+		return nil
+	}
+	if media := response.Content[api.ContentTypeJSON]; media != nil && media.Schema != nil {
+		return media.Schema
+	}
+	for _, media := range response.Content {
+		if media != nil && media.Schema != nil {
+			return media.Schema
+		}
+	}
+	return nil
+}
+
+// Validation options for responses, which are validated even when request validation is disabled.
+type responseValidationProvider struct {
+	site *Site
+}
+
+func (p responseValidationProvider) ValidationOptions(typ reflect.Type) ValidationOptions {
+	return p.site.validationOptions[typ]
+}